@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/andersfylling/disgord"
 	"github.com/meooow25/cfspy/bot"
 	"github.com/meooow25/cfspy/fetch"
+	"github.com/meooow25/cfspy/mathimg"
 )
 
 // Length limits for short preview of blog/comments. Much lower than Discord message limits.
@@ -15,11 +19,37 @@ const (
 	msgSlack = 100
 )
 
+// Length limit for each page of content too long for one embed, within Discord's embed description
+// limit.
+const contentPageLimit = 2000
+
+// Widget kind for blog and comment previews.
+const blogWidgetKind = "blog"
+
+// Renders display math in blogs and comments to images, nil if disabled.
+var mathRenderer *mathimg.Renderer
+
+// Number of formulas kept rendered by mathRenderer.
+const mathCacheSize = 500
+
+// Maximum number of images after the first shown on pages of their own.
+const maxImagePages = 10
+
+// Maximum number of parent comments shown on pages before a reply.
+const maxThreadPages = 5
+
+// Length limit of the snippet of the parent comment shown with a reply.
+const parentSnippetLimit = 150
+
+// Name of the attached image of display math.
+const mathImageName = "math.png"
+
 // Installs the blog watcher feature. The bot watches for Codeforces blog and comment links and
 // responds with an embed containing info about the blog or comment.
 func installBlogAndCommentFeature(bot *bot.Bot) {
 	bot.Client.Logger().Info("Setting up CF blog and comment feature")
 	bot.OnMessageCreate(maybeHandleBlogURL)
+	bot.AddWidgetResumer(blogWidgetKind, resumeBlogWidget)
 }
 
 func maybeHandleBlogURL(ctx *bot.Context, evt *disgord.MessageCreate) {
@@ -41,27 +71,69 @@ func maybeHandleBlogURL(ctx *bot.Context, evt *disgord.MessageCreate) {
 func handleBlogURL(ctx *bot.Context, blogURL string) {
 	ctx.Logger.Info("Processing blog URL: ", blogURL)
 
-	blogInfo, err := fetch.Blog(context.Background(), blogURL)
+	params, err := makeBlogPreviewParams(ctx, blogURL)
 	if err != nil {
-		err = fmt.Errorf("Error fetching blog from %v: %w", blogURL, err)
 		ctx.Logger.Error(err)
 		respondWithError(ctx, err)
 		return
 	}
+	if err = respondWithPreview(ctx, blogWidgetKind, blogURL, params); err != nil {
+		ctx.Logger.Error(fmt.Errorf("Error sending blog info: %w", err))
+	}
+}
 
-	short, full := makeBlogEmbeds(blogInfo)
-	var page *bot.Page
-	if full != nil {
-		page = bot.NewPageWithExpansion("", short, "", full)
-	} else {
-		page = bot.NewPage("", short)
+// Rebuilds a blog or comment preview from its URL.
+func resumeBlogWidget(ctx *bot.Context, state *bot.WidgetState) (*bot.WidgetParams, error) {
+	blogURLMatches := fetch.ParseBlogURLs(state.Source)
+	if len(blogURLMatches) == 0 {
+		return nil, fmt.Errorf("Not a blog URL: %v", state.Source)
 	}
-	if err = respondWithOnePagePreview(ctx, page); err != nil {
-		ctx.Logger.Error(fmt.Errorf("Error sending blog info: %w", err))
+	first := blogURLMatches[0]
+	if first.CommentID != "" {
+		return makeCommentPreviewParams(ctx, first.URL, first.CommentID)
+	}
+	return makeBlogPreviewParams(ctx, first.URL)
+}
+
+func makeBlogPreviewParams(ctx *bot.Context, blogURL string) (*bot.WidgetParams, error) {
+	revisionCount, infoGetter, err := fetch.Blog(context.Background(), blogURL)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching blog from %v: %w", blogURL, err)
+	}
+
+	latestInfo, err := infoGetter(revisionCount)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching blog from %v: %w", blogURL, err)
+	}
+	pages, files := makeLatestPages(ctx, makeBlogEmbed(latestInfo), latestInfo.Images, latestInfo.Math)
+	if revisionCount == 1 {
+		return makeMultiPagePreviewParams(ctx, pages, files...), nil
+	}
+
+	// With multiple revisions, going back from the latest pages shows a page per revision.
+	loadPage := func(_ context.Context, revision int) (*bot.Page, error) {
+		blogInfo, err := infoGetter(revision)
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching revision %v of blog %v: %w", revision, blogURL, err)
+		}
+		getPrevContent := func() (string, error) {
+			prevInfo, err := infoGetter(revision - 1)
+			if err != nil {
+				return "", fmt.Errorf(
+					"Error fetching revision %v of blog %v: %w", revision-1, blogURL, err)
+			}
+			return prevInfo.Content, nil
+		}
+		page := makeRevisionPage(makeBlogEmbed(blogInfo), revision, getPrevContent)
+		if revision == revisionCount && len(files) > 0 {
+			showMathImage(page)
+		}
+		return page, nil
 	}
+	return makeRevisionPreviewParams(ctx, loadPage, revisionCount, nil, pages, files...), nil
 }
 
-func makeBlogEmbeds(b *fetch.BlogInfo) (short *disgord.Embed, full *disgord.Embed) {
+func makeBlogEmbed(b *fetch.BlogInfo) *disgord.Embed {
 	embed := &disgord.Embed{
 		Title: b.Title,
 		URL:   b.URL,
@@ -83,7 +155,38 @@ func makeBlogEmbeds(b *fetch.BlogInfo) (short *disgord.Embed, full *disgord.Embe
 	if len(b.Images) > 0 {
 		embed.Image = &disgord.EmbedImage{URL: b.Images[0]}
 	}
-	return makeShortAndFullEmbeds(embed)
+	switch {
+	case b.CommentCount == 1:
+		embed.Footer.Text += "  •  1 comment"
+	case b.CommentCount > 1:
+		embed.Footer.Text += fmt.Sprintf("  •  %v comments", b.CommentCount)
+	}
+	if b.Contest != nil {
+		embed.Fields = append(embed.Fields, &disgord.EmbedField{
+			Name:  "Contest",
+			Value: fmt.Sprintf("[%v](%v)", b.Contest.Name, b.Contest.URL),
+		})
+	}
+	if len(b.Tags) > 0 {
+		embed.Fields = append(embed.Fields, &disgord.EmbedField{
+			Name:   "Tags",
+			Value:  strings.Join(b.Tags, ", "),
+			Inline: true,
+		})
+	}
+	if b.RevisionCount > 1 {
+		edited := fmt.Sprintf("%v revisions", b.RevisionCount)
+		if !b.LastEditTime.IsZero() {
+			// Shown in the reader's time zone by Discord.
+			edited += fmt.Sprintf(", last <t:%v:R>", b.LastEditTime.Unix())
+		}
+		embed.Fields = append(embed.Fields, &disgord.EmbedField{
+			Name:   "Edited",
+			Value:  edited,
+			Inline: true,
+		})
+	}
+	return embed
 }
 
 // Fetches the comment from the blog page, converts it to markdown and responds on the Discord
@@ -91,39 +194,161 @@ func makeBlogEmbeds(b *fetch.BlogInfo) (short *disgord.Embed, full *disgord.Embe
 func handleCommentURL(ctx *bot.Context, commentURL, commentID string) {
 	ctx.Logger.Info("Processing comment URL: ", commentURL)
 
-	revisionCount, infoGetter, err := fetch.Comment(context.Background(), commentURL, commentID)
+	params, err := makeCommentPreviewParams(ctx, commentURL, commentID)
 	if err != nil {
-		err = fmt.Errorf("Error fetching comment from %v: %w", commentURL, err)
 		ctx.Logger.Error(err)
 		respondWithError(ctx, err)
 		return
 	}
+	if err = respondWithPreview(ctx, blogWidgetKind, commentURL, params); err != nil {
+		ctx.Logger.Error(fmt.Errorf("Error sending comment preview: %w", err))
+	}
+}
 
-	getPage := func(revision int) *bot.Page {
+func makeCommentPreviewParams(
+	ctx *bot.Context,
+	commentURL string,
+	commentID string,
+) (*bot.WidgetParams, error) {
+	revisionCount, infoGetter, err := fetch.Comment(context.Background(), commentURL, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching comment from %v: %w", commentURL, err)
+	}
+
+	latestInfo, err := infoGetter(revisionCount)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching comment from %v: %w", commentURL, err)
+	}
+	pages, files := makeLatestPages(
+		ctx, makeCommentEmbed(latestInfo), latestInfo.Images, latestInfo.Math)
+	// The parents come first and the comment is shown first, so going back walks up the thread.
+	threadPages := makeThreadPages(latestInfo.Parents)
+	if revisionCount == 1 {
+		params := makeMultiPagePreviewParams(ctx, append(threadPages, pages...), files...)
+		params.Pages.First = len(threadPages) + 1
+		return params, nil
+	}
+
+	// With multiple revisions, going back past the top of the thread shows a page per revision.
+	loadPage := func(_ context.Context, revision int) (*bot.Page, error) {
 		commentInfo, err := infoGetter(revision)
 		if err != nil {
-			err := fmt.Errorf("Error fetching revision %v of comment %v: %w", revision, commentURL, err)
-			ctx.Logger.Error(err)
-			return bot.NewPage("", ctx.MakeErrorEmbed(err.Error()))
+			return nil, fmt.Errorf("Error fetching revision %v of comment %v: %w", revision, commentURL, err)
 		}
-		short, full := makeCommentEmbeds(commentInfo)
-		if full != nil {
-			return bot.NewPageWithExpansion("", short, "", full)
+		getPrevContent := func() (string, error) {
+			prevInfo, err := infoGetter(revision - 1)
+			if err != nil {
+				return "", fmt.Errorf(
+					"Error fetching revision %v of comment %v: %w", revision-1, commentURL, err)
+			}
+			return prevInfo.Content, nil
+		}
+		page := makeRevisionPage(makeCommentEmbed(commentInfo), revision, getPrevContent)
+		if revision == revisionCount && len(files) > 0 {
+			showMathImage(page)
 		}
-		return bot.NewPage("", short)
+		return page, nil
 	}
+	return makeRevisionPreviewParams(ctx, loadPage, revisionCount, threadPages, pages, files...), nil
+}
 
-	if err = respondWithMultiPagePreview(ctx, getPage, revisionCount); err != nil {
-		ctx.Logger.Error(fmt.Errorf("Error sending comment preview: %w", err))
+// Returns the params for a preview with the given pages of the latest revision, shown first,
+// preceded by the thread pages of a comment if any and before them a page per revision. Revision
+// pages are loaded when needed, since revisions other than the latest are fetched on demand, which
+// takes a while. Each revision page shows the full content if it fits and only the first image of
+// the revision.
+func makeRevisionPreviewParams(
+	ctx *bot.Context,
+	loadPage func(ctx context.Context, revision int) (*bot.Page, error),
+	revisionCount int,
+	threadPages []*bot.Page,
+	latestPages []*bot.Page,
+	files ...disgord.CreateMessageFileParams,
+) *bot.WidgetParams {
+	errorPage := func(_ int, err error) *bot.Page {
+		ctx.Logger.Error(err)
+		return bot.NewPage("", ctx.MakeErrorEmbed(err.Error()))
 	}
+	pages := append(append([]*bot.Page{}, threadPages...), latestPages...)
+	getPage := func(pageNum int) *bot.Page {
+		if pageNum <= revisionCount {
+			return nil
+		}
+		return pages[pageNum-revisionCount-1]
+	}
+	// Pages other than revisions are numbered among themselves.
+	indicator := func(pageNum, _ int) string {
+		if pageNum <= revisionCount {
+			return fmt.Sprintf("Revision %v/%v", pageNum, revisionCount)
+		}
+		var parts []string
+		if pageNum > revisionCount+len(threadPages) {
+			parts = append(parts, "Latest revision")
+		}
+		if len(pages) > 1 {
+			parts = append(parts, bot.PageIndicator(pageNum-revisionCount, len(pages)))
+		}
+		return strings.Join(parts, "  •  ")
+	}
+	return makePreviewParams(ctx, &bot.Pages{
+		Get:       getPage,
+		Load:      loadPage,
+		Total:     revisionCount + len(pages),
+		First:     revisionCount + len(threadPages) + 1,
+		ErrorPage: errorPage,
+		Files:     files,
+		Indicator: indicator,
+	})
 }
 
-func makeCommentEmbeds(c *fetch.CommentInfo) (short *disgord.Embed, full *disgord.Embed) {
-	revisionStr := ""
-	if c.RevisionCount > 1 {
-		revisionStr = fmt.Sprintf(
-			"  •  Revision %v/%v", c.Revision, c.RevisionCount)
+// Returns the page for a revision, showing the full content if it fits. Expanding the page shows
+// the content with the changes from the previous revision marked, which is fetched only then.
+func makeRevisionPage(
+	embed *disgord.Embed,
+	revision int,
+	getPrevContent func() (string, error),
+) *bot.Page {
+	shown := embed
+	if bot.EmbedDescriptionTooLong(embed) {
+		shown = disgord.DeepCopy(embed).(*disgord.Embed)
+		shown.Description = truncate(embed.Description)
+	}
+	page := bot.NewPage("", shown)
+	if revision > 1 {
+		page.LoadExpanded = func(context.Context) (*bot.Message, error) {
+			prevContent, err := getPrevContent()
+			if err != nil {
+				return nil, err
+			}
+			diff := makeRevisionDiffEmbed(embed, prevContent, revision-1)
+			if diff == nil {
+				return nil, fmt.Errorf("Changes from revision %v are too long to show", revision-1)
+			}
+			return &bot.Message{Embed: diff}, nil
+		}
 	}
+	return page
+}
+
+// Returns the embed with the changes in the content from the previous revision marked, or nil if
+// that is too long to show.
+func makeRevisionDiffEmbed(
+	embed *disgord.Embed,
+	prevContent string,
+	prevRevision int,
+) *disgord.Embed {
+	diff := *embed
+	diff.Description = diffWordsMarkdown(prevContent, embed.Description)
+	footer := *embed.Footer
+	footer.Text += fmt.Sprintf("  •  Changes from revision %v", prevRevision)
+	diff.Footer = &footer
+	if bot.EmbedDescriptionTooLong(&diff) {
+		return nil
+	}
+	return &diff
+}
+
+func makeCommentEmbed(c *fetch.CommentInfo) *disgord.Embed {
 	embed := &disgord.Embed{
 		Title: c.BlogTitle,
 		URL:   c.URL,
@@ -138,14 +363,143 @@ func makeCommentEmbeds(c *fetch.CommentInfo) (short *disgord.Embed, full *disgor
 			Time: c.CreationTime,
 		},
 		Footer: &disgord.EmbedFooter{
-			Text: fmt.Sprintf("Score %+d%s", c.Rating, revisionStr),
+			Text: fmt.Sprintf("Score %+d", c.Rating),
 		},
 		Color: c.AuthorColor,
 	}
 	if len(c.Images) > 0 {
 		embed.Image = &disgord.EmbedImage{URL: c.Images[0]}
 	}
-	return makeShortAndFullEmbeds(embed)
+	switch {
+	case c.ReplyCount == 1:
+		embed.Footer.Text += "  •  1 reply"
+	case c.ReplyCount > 1:
+		embed.Footer.Text += fmt.Sprintf("  •  %v replies", c.ReplyCount)
+	}
+	if len(c.Parents) > 0 {
+		parent := c.Parents[0]
+		embed.Fields = []*disgord.EmbedField{{
+			Name:  "In reply to " + parent.AuthorHandle,
+			Value: makeParentSnippet(parent.Content),
+		}}
+	}
+	return embed
+}
+
+// Returns a short quote of the parent comment's content, on one line.
+func makeParentSnippet(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if content == "" {
+		return "*No text*"
+	}
+	return "> " + bot.TruncateMarkdown(content, parentSnippetLimit)
+}
+
+// Returns pages for the parents of a comment, farthest first.
+func makeThreadPages(parents []*fetch.CommentInfo) []*bot.Page {
+	if len(parents) > maxThreadPages {
+		parents = parents[:maxThreadPages]
+	}
+	var pages []*bot.Page
+	for i := len(parents) - 1; i >= 0; i-- {
+		embed := makeCommentEmbed(parents[i])
+		if i == 0 {
+			embed.Footer.Text += "  •  Parent comment"
+		} else {
+			embed.Footer.Text += fmt.Sprintf("  •  %v levels up", i+1)
+		}
+		short, full := makeShortAndFullEmbeds(embed)
+		if full != nil {
+			pages = append(pages, bot.NewPageWithExpansion("", short, "", full))
+		} else {
+			pages = append(pages, bot.NewPage("", short))
+		}
+	}
+	return pages
+}
+
+// Returns the content and image pages for the latest revision of a blog or comment, and the image
+// of its display math to attach if there is any.
+func makeLatestPages(
+	ctx *bot.Context,
+	embed *disgord.Embed,
+	images []string,
+	formulas []string,
+) ([]*bot.Page, []disgord.CreateMessageFileParams) {
+	pages := makeContentPages(embed)
+	files := makeMathImageFiles(ctx, formulas)
+	if len(files) > 0 {
+		showMathImage(pages[0])
+	}
+	return append(pages, makeImagePages(embed, images)...), files
+}
+
+// Returns the pages for a blog or comment embed. Content that fits in one embed is shown on one page
+// that can be expanded. Longer content is split into pages that follow the short preview.
+func makeContentPages(embed *disgord.Embed) []*bot.Page {
+	short, full := makeShortAndFullEmbeds(embed)
+	if full != nil {
+		return []*bot.Page{bot.NewPageWithExpansion("", short, "", full)}
+	}
+	pages := []*bot.Page{bot.NewPage("", short)}
+	if !bot.EmbedDescriptionTooLong(embed) {
+		return pages
+	}
+	for _, part := range bot.SplitMarkdown(embed.Description, contentPageLimit) {
+		// The image is shown on the preview only.
+		partEmbed := *embed
+		partEmbed.Description = part
+		partEmbed.Image = nil
+		pages = append(pages, bot.NewPage("", &partEmbed))
+	}
+	return pages
+}
+
+// Returns an image of the display math formulas to attach when the preview is first sent, if math
+// rendering is enabled. The image is not attached to pages, since moving to or from a page with
+// files replaces the widget message. Pages show it with showMathImage.
+func makeMathImageFiles(ctx *bot.Context, formulas []string) []disgord.CreateMessageFileParams {
+	if mathRenderer == nil || len(formulas) == 0 {
+		return nil
+	}
+	data, err := mathRenderer.RenderPNG(formulas)
+	if err != nil {
+		ctx.Logger.Info("Not rendering math: ", err)
+		return nil
+	}
+	return []disgord.CreateMessageFileParams{{
+		Reader:   bytes.NewReader(data),
+		FileName: mathImageName,
+	}}
+}
+
+// Shows the attached image of display math in the embeds of the page that have no image already.
+func showMathImage(page *bot.Page) {
+	for _, msg := range []*bot.Message{page.Default, page.Expanded} {
+		if msg != nil && msg.Embed != nil && msg.Embed.Image == nil {
+			msg.Embed.Image = &disgord.EmbedImage{URL: "attachment://" + mathImageName}
+		}
+	}
+}
+
+// Returns a page for each image after the first, which is shown on the first page. Images beyond
+// maxImagePages pages are skipped, which the pages say.
+func makeImagePages(embed *disgord.Embed, images []string) []*bot.Page {
+	shown := len(images)
+	if shown > maxImagePages+1 {
+		shown = maxImagePages + 1
+	}
+	var pages []*bot.Page
+	for i := 1; i < shown; i++ {
+		imageEmbed := *embed
+		imageEmbed.Description = fmt.Sprintf("Image %v of %v", i+1, shown)
+		if skipped := len(images) - shown; skipped > 0 {
+			imageEmbed.Description += fmt.Sprintf(", %v more not shown", skipped)
+		}
+		imageEmbed.Image = &disgord.EmbedImage{URL: images[i]}
+		pages = append(pages, bot.NewPage("", &imageEmbed))
+	}
+	return pages
 }
 
 func makeShortAndFullEmbeds(embed *disgord.Embed) (short *disgord.Embed, full *disgord.Embed) {
@@ -161,15 +515,12 @@ func makeShortAndFullEmbeds(embed *disgord.Embed) (short *disgord.Embed, full *d
 	return
 }
 
-// Returns the string unchanged if the length is within msgLimit+msgSlack, otherwise returns it
+// Returns the markdown unchanged if the length is within msgLimit+msgSlack, otherwise returns it
 // truncated to msgLimit chars. The motivation for the slack is that the poster would probably want
 // to display the full content anyway if it is a bit over the limit.
 func truncate(s string) string {
-	if len(s) <= msgLimit+msgSlack {
+	if utf8.RuneCountInString(s) <= msgLimit+msgSlack {
 		return s
 	}
-	// Cutting off everything beyond limit doesn't care about markdown formatting and can leave
-	// unclosed markup.
-	// TODO: Maybe use a markdown parser to properly handle these.
-	return s[:msgLimit] + "…"
+	return bot.TruncateMarkdown(s, msgLimit)
 }
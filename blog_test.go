@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/meooow25/cfspy/bot"
+	"github.com/meooow25/cfspy/fetch"
+)
+
+func TestMakeContentPages(t *testing.T) {
+	newEmbed := func(content string) *disgord.Embed {
+		return &disgord.Embed{
+			Title:       "title",
+			Description: content,
+			Image:       &disgord.EmbedImage{URL: "image"},
+		}
+	}
+
+	// Short, one page
+	pages := makeContentPages(newEmbed("short"))
+	if len(pages) != 1 || pages[0].Expanded != nil {
+		t.Fatalf("got %v pages, want one page without expansion", len(pages))
+	}
+
+	// Fits in one embed, one page that expands
+	pages = makeContentPages(newEmbed(strings.Repeat("word ", 100)))
+	if len(pages) != 1 || pages[0].Expanded == nil {
+		t.Fatalf("got %v pages, want one page with expansion", len(pages))
+	}
+
+	// Too long, the preview then the content over pages
+	paragraph := strings.TrimSpace(strings.Repeat("word ", 100)) + "\n\n"
+	content := strings.Repeat(paragraph, 10) // 3 paragraphs fit on a page
+	pages = makeContentPages(newEmbed(content))
+	if len(pages) != 5 {
+		t.Fatalf("got %v pages, want 5", len(pages))
+	}
+	if pages[0].Default.Embed.Description != truncate(content) {
+		t.Fatalf("got first page %q, want the short preview", pages[0].Default.Embed.Description)
+	}
+	var parts []string
+	for _, page := range pages[1:] {
+		embed := page.Default.Embed
+		if page.Expanded != nil || embed.Image != nil || embed.Title != "title" {
+			t.Fatalf("got page %+v, want content page without image or expansion", page)
+		}
+		if bot.EmbedDescriptionTooLong(embed) {
+			t.Fatalf("page too long: %v chars", len(embed.Description))
+		}
+		parts = append(parts, embed.Description)
+	}
+	if got, want := strings.Join(parts, "\n\n"), strings.TrimSpace(content); got != want {
+		t.Fatal("pages don't have the full content")
+	}
+}
+
+func TestMakeImagePages(t *testing.T) {
+	embed := &disgord.Embed{
+		Title:       "title",
+		Description: "content",
+		Image:       &disgord.EmbedImage{URL: "image1"},
+	}
+	if pages := makeImagePages(embed, []string{"image1"}); len(pages) != 0 {
+		t.Fatalf("got %v pages, want none for one image", len(pages))
+	}
+
+	pages := makeImagePages(embed, []string{"image1", "image2", "image3"})
+	if len(pages) != 2 {
+		t.Fatalf("got %v pages, want 2", len(pages))
+	}
+	for i, page := range pages {
+		got := page.Default.Embed
+		wantURL := fmt.Sprintf("image%v", i+2)
+		wantDesc := fmt.Sprintf("Image %v of 3", i+2)
+		if got.Image.URL != wantURL || got.Description != wantDesc || got.Title != "title" {
+			t.Fatalf("got image %v with description %q, want %v with %q",
+				got.Image.URL, got.Description, wantURL, wantDesc)
+		}
+	}
+	if embed.Image.URL != "image1" || embed.Description != "content" {
+		t.Fatal("embed modified")
+	}
+
+	var images []string
+	for i := 0; i < 20; i++ {
+		images = append(images, fmt.Sprint(i))
+	}
+	pages = makeImagePages(embed, images)
+	if len(pages) != maxImagePages {
+		t.Fatalf("got %v pages, want %v", len(pages), maxImagePages)
+	}
+	wantDesc := fmt.Sprintf("Image %v of %v, %v more not shown",
+		maxImagePages+1, maxImagePages+1, 20-maxImagePages-1)
+	if got := pages[maxImagePages-1].Default.Embed.Description; got != wantDesc {
+		t.Fatalf("got %q, want %q", got, wantDesc)
+	}
+}
+
+func TestMakeThreadPages(t *testing.T) {
+	var parents []*fetch.CommentInfo
+	for i := 0; i < maxThreadPages+2; i++ {
+		parents = append(parents, &fetch.CommentInfo{
+			Content:      fmt.Sprintf("parent %v", i),
+			AuthorHandle: fmt.Sprintf("handle%v", i),
+		})
+	}
+	reply := &fetch.CommentInfo{Content: "reply", Parents: parents, ReplyCount: 2}
+
+	embed := makeCommentEmbed(reply)
+	if len(embed.Fields) != 1 || embed.Fields[0].Name != "In reply to handle0" ||
+		embed.Fields[0].Value != "> parent 0" {
+		t.Fatalf("got fields %v, want a snippet of the parent", embed.Fields)
+	}
+	if embed.Footer.Text != "Score +0  •  2 replies" {
+		t.Fatalf("got footer %q", embed.Footer.Text)
+	}
+
+	pages := makeThreadPages(parents)
+	if len(pages) != maxThreadPages {
+		t.Fatalf("got %v pages, want %v", len(pages), maxThreadPages)
+	}
+	for i, page := range pages {
+		got := page.Default.Embed
+		want := fmt.Sprintf("parent %v", maxThreadPages-1-i)
+		if got.Description != want {
+			t.Fatalf("got page %v with %q, want %q", i, got.Description, want)
+		}
+	}
+	if got := pages[len(pages)-1].Default.Embed.Footer.Text; !strings.HasSuffix(got, "Parent comment") {
+		t.Fatalf("got footer %q for the parent", got)
+	}
+}
+
+func TestMakeBlogEmbed(t *testing.T) {
+	embed := makeBlogEmbed(&fetch.BlogInfo{
+		Rating:        10,
+		Tags:          []string{"div2", "editorial"},
+		CommentCount:  5,
+		RevisionCount: 3,
+		LastEditTime:  time.Unix(1600000000, 0),
+		Contest:       &fetch.BlogContest{Name: "Round 1", URL: "https://codeforces.com/contest/1"},
+	})
+	if embed.Footer.Text != "Score +10  •  5 comments" {
+		t.Fatalf("got footer %q", embed.Footer.Text)
+	}
+	want := []*disgord.EmbedField{
+		{Name: "Contest", Value: "[Round 1](https://codeforces.com/contest/1)"},
+		{Name: "Tags", Value: "div2, editorial", Inline: true},
+		{Name: "Edited", Value: "3 revisions, last <t:1600000000:R>", Inline: true},
+	}
+	if len(embed.Fields) != len(want) {
+		t.Fatalf("got %v fields, want %v", len(embed.Fields), len(want))
+	}
+	for i, field := range embed.Fields {
+		if *field != *want[i] {
+			t.Fatalf("got field %+v, want %+v", *field, *want[i])
+		}
+	}
+
+	if embed := makeBlogEmbed(&fetch.BlogInfo{RevisionCount: 1}); len(embed.Fields) != 0 {
+		t.Fatalf("got fields %v, want none", embed.Fields)
+	}
+}
+
+func TestMakeRevisionPage(t *testing.T) {
+	embed := &disgord.Embed{
+		Description: "a b d",
+		Footer:      &disgord.EmbedFooter{Text: "Score +1"},
+	}
+	fetched := false
+	getPrevContent := func() (string, error) {
+		fetched = true
+		return "a c d", nil
+	}
+	if page := makeRevisionPage(embed, 1, getPrevContent); page.LoadExpanded != nil {
+		t.Fatal("got an expansion for the first revision")
+	}
+
+	page := makeRevisionPage(embed, 3, getPrevContent)
+	if page.Default.Embed.Description != "a b d" {
+		t.Fatalf("got %q, want the content", page.Default.Embed.Description)
+	}
+	if fetched {
+		t.Fatal("previous revision fetched before expanding")
+	}
+	expanded, err := page.LoadExpanded(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := expanded.Embed
+	wantFooter := "Score +1  •  Changes from revision 2"
+	if got.Description != "a ~~c~~ **b** d" || got.Footer.Text != wantFooter {
+		t.Fatalf("got %q with footer %q, want the changes", got.Description, got.Footer.Text)
+	}
+	if embed.Footer.Text != "Score +1" {
+		t.Fatal("embed modified")
+	}
+
+	failing := func() (string, error) { return "", errors.New("fetch failed") }
+	if _, err := makeRevisionPage(embed, 3, failing).LoadExpanded(context.Background()); err == nil {
+		t.Fatal("got no error when the previous revision failed to fetch")
+	}
+}
+
+func TestMakeRevisionPreviewParams(t *testing.T) {
+	latestPages := []*bot.Page{
+		bot.NewPage("", &disgord.Embed{Description: "content"}),
+		bot.NewPage("", &disgord.Embed{Description: "image"}),
+	}
+	loadPage := func(_ context.Context, revision int) (*bot.Page, error) {
+		return bot.NewPage("", &disgord.Embed{Description: fmt.Sprint(revision)}), nil
+	}
+	pages := makeRevisionPreviewParams(&bot.Context{}, loadPage, 3, nil, latestPages).Pages
+	if pages.Total != 5 || pages.First != 4 {
+		t.Fatalf("got total %v and first %v, want 5 and 4", pages.Total, pages.First)
+	}
+	if pages.Get(3) != nil || pages.Get(4) != latestPages[0] || pages.Get(5) != latestPages[1] {
+		t.Fatal("want revision pages loaded and the latest pages got")
+	}
+	for pageNum, want := range map[int]string{
+		1: "Revision 1/3",
+		3: "Revision 3/3",
+		5: "Latest revision  •  Page 2/2",
+	} {
+		if got := pages.Indicator(pageNum, pages.Total); got != want {
+			t.Fatalf("got indicator %q for page %v, want %q", got, pageNum, want)
+		}
+	}
+
+	// Going back from the comment walks up the thread before the revisions.
+	threadPages := []*bot.Page{bot.NewPage("", &disgord.Embed{Description: "parent"})}
+	pages = makeRevisionPreviewParams(&bot.Context{}, loadPage, 3, threadPages, latestPages[:1]).Pages
+	if pages.Total != 5 || pages.First != 5 || pages.Get(4) != threadPages[0] {
+		t.Fatalf("got total %v and first %v, want 5 and 5 after the thread", pages.Total, pages.First)
+	}
+	for pageNum, want := range map[int]string{
+		3: "Revision 3/3",
+		4: "Page 1/2",
+		5: "Latest revision  •  Page 2/2",
+	} {
+		if got := pages.Indicator(pageNum, pages.Total); got != want {
+			t.Fatalf("got indicator %q for page %v, want %q", got, pageNum, want)
+		}
+	}
+}
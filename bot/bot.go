@@ -1,8 +1,11 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/andersfylling/disgord"
 	"github.com/andersfylling/disgord/std"
@@ -15,6 +18,8 @@ type Bot struct {
 	commands    map[string]*Command
 	commandList []*Command
 	helpCommand *Command
+	resumers    map[string]WidgetResumer
+	resumeOnce  sync.Once
 }
 
 // Info wraps some bot info.
@@ -24,6 +29,9 @@ type Info struct {
 	Prefix      string
 	Description string
 	SupportURL  string
+
+	// Optional store for widgets, so that they can be resumed after a restart.
+	WidgetStore WidgetStore
 }
 
 // Command represents a bot command.
@@ -34,6 +42,11 @@ type Command struct {
 	Handler     func(*Context)
 }
 
+// WidgetResumer rebuilds the params of a persisted widget after a restart. ctx.Message is a partial
+// message with only the ID, channel ID, guild ID and author ID of the message that triggered the
+// widget.
+type WidgetResumer func(ctx *Context, state *WidgetState) (*WidgetParams, error)
+
 // Used for parsing args, the string `hello "wor ld"` will be parsed to ["hello", "wor ld"]
 var argsRe = regexp.MustCompile(`"([^"]+)"|([^\s]+)`)
 
@@ -43,6 +56,7 @@ func New(info Info) *Bot {
 		Client:   disgord.New(info.Config),
 		Info:     info,
 		commands: make(map[string]*Command),
+		resumers: make(map[string]WidgetResumer),
 	}
 	bot.helpCommand = &Command{
 		ID:          "help",
@@ -53,6 +67,12 @@ func New(info Info) *Bot {
 		WithMiddleware(
 			filterMsgCreateNotBot, std.CopyMsgEvt, filterMsgCreateStripPrefix(bot.Info.Prefix)).
 		MessageCreate(bot.maybeHandleCommand)
+	if info.WidgetStore != nil {
+		// Ready fires again on reconnects, but the persisted widgets only need to be resumed once.
+		bot.Client.Gateway().BotReady(func() {
+			bot.resumeOnce.Do(func() { go bot.resumeWidgets() })
+		})
+	}
 	return &bot
 }
 
@@ -76,6 +96,54 @@ func (bot *Bot) AddCommand(command *Command) {
 	bot.commandList = append(bot.commandList, command)
 }
 
+// AddWidgetResumer adds a WidgetResumer for persisted widgets of the given kind.
+func (bot *Bot) AddWidgetResumer(kind string, resumer WidgetResumer) {
+	bot.resumers[kind] = resumer
+}
+
+func (bot *Bot) resumeWidgets() {
+	logger := bot.Client.Logger()
+	states, err := bot.Info.WidgetStore.LoadAll()
+	if err != nil {
+		logger.Error(fmt.Errorf("Loading persisted widgets failed: %w", err))
+		return
+	}
+	logger.Info("Resuming persisted widgets: ", len(states))
+	for _, state := range states {
+		go bot.resumeWidget(state)
+	}
+}
+
+// Resumes control of the widget if it has not expired, otherwise cleans it up.
+func (bot *Bot) resumeWidget(state *WidgetState) {
+	w := widget{
+		messager: &disgordMessager{session: bot.Client},
+		logger:   bot.Client.Logger(),
+		store:    bot.Info.WidgetStore,
+	}
+	if resumer, ok := bot.resumers[state.Kind]; ok && time.Now().Before(state.Expiry) {
+		ctx := &Context{
+			Bot:     bot,
+			Session: bot.Client,
+			Message: &disgord.Message{
+				ID:        state.TriggerMessageID,
+				ChannelID: state.ChannelID,
+				GuildID:   state.GuildID,
+				Author:    &disgord.User{ID: state.TriggerAuthorID},
+			},
+			Logger: w.logger,
+		}
+		var err error
+		if w.params, err = resumer(ctx, state); err == nil {
+			if err = w.resume(context.Background(), state); err == nil {
+				return
+			}
+		}
+		w.logger.Error(fmt.Errorf("Resuming widget %v failed: %w", state.MessageID, err))
+	}
+	cleanupPersistedWidget(context.Background(), state, w.store, w.messager, w.logger)
+}
+
 func (bot *Bot) maybeHandleCommand(s disgord.Session, evt *disgord.MessageCreate) {
 	ctx := &Context{
 		Bot:     bot,
@@ -70,7 +70,23 @@ func (ctx *Context) MakeErrorEmbed(msg string) *disgord.Embed {
 	return &embed
 }
 
-// SendPaginated sends a paginated widget in the current channel.
+// SendWidget sends a paginated widget in the current channel. The widget is persisted if the bot
+// has a WidgetStore and params.Kind is set.
 func (ctx *Context) SendWidget(params *WidgetParams) error {
-	return SendPaginated(context.Background(), params, ctx.Session, ctx.Message.ChannelID)
+	w := widget{
+		params:   params,
+		messager: &disgordMessager{session: ctx.Session},
+		logger:   ctx.Logger,
+	}
+	if store := ctx.Bot.Info.WidgetStore; store != nil && params.Kind != "" {
+		w.store = store
+		w.state = &WidgetState{
+			Kind:             params.Kind,
+			Source:           params.Source,
+			GuildID:          ctx.Message.GuildID,
+			TriggerMessageID: ctx.Message.ID,
+			TriggerAuthorID:  ctx.Message.Author.ID,
+		}
+	}
+	return w.run(context.Background(), ctx.Message.ChannelID)
 }
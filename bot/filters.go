@@ -39,3 +39,14 @@ func filterReactionAddForMsg(msgID disgord.Snowflake) disgord.Middleware {
 		return evt
 	}
 }
+
+// Returns a filter for MessageCreate events, which allows replies to the given message ID only.
+func filterMsgCreateReplyTo(msgID disgord.Snowflake) disgord.Middleware {
+	return func(evt interface{}) interface{} {
+		ref := evt.(*disgord.MessageCreate).Message.MessageReference
+		if ref == nil || ref.MessageID != msgID {
+			return nil
+		}
+		return evt
+	}
+}
@@ -1,10 +1,16 @@
 package bot
 
-import "github.com/andersfylling/disgord"
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/andersfylling/disgord"
+)
 
 // See
 // https://discord.com/developers/docs/resources/channel#create-message
 // https://discord.com/developers/docs/resources/channel#embed-limits
+// Limits are on the number of characters, not bytes.
 const (
 	contentCharLimit = 2000
 
@@ -15,15 +21,169 @@ const (
 	fieldValueCharLimit  = 1024
 	footerTextCharLimit  = 2048
 	authorNameCharLimit  = 256
+	embedTotalCharLimit  = 6000
 )
 
 // ContentTooLong checks whether the given message content is longer than the acceptable limit.
 func ContentTooLong(content string) bool {
-	return len(content) > contentCharLimit
+	return utf8.RuneCountInString(content) > contentCharLimit
 }
 
 // EmbedDescriptionTooLong checks whether the given embed has description longer than the acceptable
 // limit.
 func EmbedDescriptionTooLong(embed *disgord.Embed) bool {
-	return len(embed.Description) > descriptionCharLimit
+	return utf8.RuneCountInString(embed.Description) > descriptionCharLimit
+}
+
+// ValidateEmbed checks that the given embed is within all of Discord's embed limits, and returns an
+// error describing the first limit exceeded otherwise.
+func ValidateEmbed(embed *disgord.Embed) error {
+	check := func(what, s string, limit int) error {
+		if n := utf8.RuneCountInString(s); n > limit {
+			return fmt.Errorf("Embed %v too long, %v chars exceeds %v", what, n, limit)
+		}
+		return nil
+	}
+	if err := check("title", embed.Title, titleCharLimit); err != nil {
+		return err
+	}
+	if err := check("description", embed.Description, descriptionCharLimit); err != nil {
+		return err
+	}
+	if len(embed.Fields) > fieldCountLimit {
+		return fmt.Errorf(
+			"Embed has too many fields, %v exceeds %v", len(embed.Fields), fieldCountLimit)
+	}
+	for i, field := range embed.Fields {
+		if err := check(fmt.Sprintf("field %v name", i+1), field.Name, fieldNameCharLimit); err != nil {
+			return err
+		}
+		if err := check(fmt.Sprintf("field %v value", i+1), field.Value, fieldValueCharLimit); err != nil {
+			return err
+		}
+	}
+	if embed.Footer != nil {
+		if err := check("footer text", embed.Footer.Text, footerTextCharLimit); err != nil {
+			return err
+		}
+	}
+	if embed.Author != nil {
+		if err := check("author name", embed.Author.Name, authorNameCharLimit); err != nil {
+			return err
+		}
+	}
+	if n := embedLength(embed); n > embedTotalCharLimit {
+		return fmt.Errorf("Embed too long, %v chars in total exceeds %v", n, embedTotalCharLimit)
+	}
+	return nil
+}
+
+// FitEmbed returns the embed unchanged if it is within limits, otherwise returns a copy truncated to
+// fit. Fields beyond the limit are dropped and every part is truncated to its own limit, without
+// breaking markdown where possible. If the embed is still too long in total, parts are truncated
+// further in the order description, field values from the last, field names from the last, footer
+// text, author name, title.
+func FitEmbed(embed *disgord.Embed) *disgord.Embed {
+	if ValidateEmbed(embed) == nil {
+		return embed
+	}
+
+	// Copy only what is modified, the embed belongs to the caller.
+	fitted := *embed
+	fitted.Fields = nil
+	for i, field := range embed.Fields {
+		if i == fieldCountLimit {
+			break
+		}
+		fieldCopy := *field
+		fitted.Fields = append(fitted.Fields, &fieldCopy)
+	}
+	if embed.Footer != nil {
+		footerCopy := *embed.Footer
+		fitted.Footer = &footerCopy
+	}
+	if embed.Author != nil {
+		authorCopy := *embed.Author
+		fitted.Author = &authorCopy
+	}
+
+	type part struct {
+		s        *string
+		limit    int
+		minChars int // Field names and values must not be empty
+	}
+	var parts []part
+	parts = append(parts, part{&fitted.Description, descriptionCharLimit, 0})
+	for i := len(fitted.Fields) - 1; i >= 0; i-- {
+		parts = append(parts, part{&fitted.Fields[i].Value, fieldValueCharLimit, 1})
+	}
+	for i := len(fitted.Fields) - 1; i >= 0; i-- {
+		parts = append(parts, part{&fitted.Fields[i].Name, fieldNameCharLimit, 1})
+	}
+	if fitted.Footer != nil {
+		parts = append(parts, part{&fitted.Footer.Text, footerTextCharLimit, 0})
+	}
+	if fitted.Author != nil {
+		parts = append(parts, part{&fitted.Author.Name, authorNameCharLimit, 0})
+	}
+	parts = append(parts, part{&fitted.Title, titleCharLimit, 0})
+
+	for _, p := range parts {
+		*p.s = truncateMarkdownOrChars(*p.s, p.limit)
+	}
+	excess := embedLength(&fitted) - embedTotalCharLimit
+	for _, p := range parts {
+		if excess <= 0 {
+			break
+		}
+		n := utf8.RuneCountInString(*p.s)
+		keep := n - excess
+		if keep < p.minChars {
+			keep = p.minChars
+		}
+		*p.s = truncateMarkdownOrChars(*p.s, keep)
+		excess -= n - utf8.RuneCountInString(*p.s)
+	}
+	return &fitted
+}
+
+// FitContent returns the content truncated to the message content limit.
+func FitContent(content string) string {
+	return truncateMarkdownOrChars(content, contentCharLimit)
+}
+
+// Returns the total number of characters in the embed as counted towards the total limit.
+func embedLength(embed *disgord.Embed) int {
+	n := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, field := range embed.Fields {
+		n += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	if embed.Footer != nil {
+		n += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		n += utf8.RuneCountInString(embed.Author.Name)
+	}
+	return n
+}
+
+// Returns s truncated like TruncateMarkdown so that formatting is not broken, or like truncateChars
+// if there is nowhere to cut the markdown.
+func truncateMarkdownOrChars(s string, limit int) string {
+	if truncated := TruncateMarkdown(s, limit); truncated != "" {
+		return truncated
+	}
+	return truncateChars(s, limit)
+}
+
+// Returns s unchanged if it has at most limit chars, otherwise truncated to limit chars ending with
+// an ellipsis.
+func truncateChars(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	if limit <= 0 {
+		return ""
+	}
+	return string([]rune(s)[:limit-1]) + "…"
 }
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/andersfylling/disgord"
+	"github.com/go-test/deep"
+)
+
+func makeFields(n int, name, value string) []*disgord.EmbedField {
+	var fields []*disgord.EmbedField
+	for i := 0; i < n; i++ {
+		fields = append(fields, &disgord.EmbedField{Name: name, Value: value})
+	}
+	return fields
+}
+
+func TestValidateEmbed(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		embed   *disgord.Embed
+		wantErr bool
+	}{
+		{"empty", &disgord.Embed{}, false},
+		{"title at limit", &disgord.Embed{Title: strings.Repeat("a", 256)}, false},
+		{"title too long", &disgord.Embed{Title: strings.Repeat("a", 257)}, true},
+		// 2048 chars but 4096 bytes
+		{"cyrillic description", &disgord.Embed{Description: strings.Repeat("я", 2048)}, false},
+		{"description too long", &disgord.Embed{Description: strings.Repeat("я", 2049)}, true},
+		{"25 fields", &disgord.Embed{Fields: makeFields(25, "n", "v")}, false},
+		{"26 fields", &disgord.Embed{Fields: makeFields(26, "n", "v")}, true},
+		{
+			"field name too long",
+			&disgord.Embed{Fields: makeFields(1, strings.Repeat("a", 257), "v")},
+			true,
+		},
+		{
+			"field value too long",
+			&disgord.Embed{Fields: makeFields(1, "n", strings.Repeat("a", 1025))},
+			true,
+		},
+		{
+			"footer too long",
+			&disgord.Embed{Footer: &disgord.EmbedFooter{Text: strings.Repeat("a", 2049)}},
+			true,
+		},
+		{
+			"author name too long",
+			&disgord.Embed{Author: &disgord.EmbedAuthor{Name: strings.Repeat("a", 257)}},
+			true,
+		},
+		{
+			"total too long",
+			&disgord.Embed{
+				Description: strings.Repeat("a", 2000),
+				Fields:      makeFields(4, "n", strings.Repeat("a", 1000)),
+			},
+			true,
+		},
+	} {
+		err := ValidateEmbed(test.embed)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want error %v", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestFitEmbed(t *testing.T) {
+	// Within limits, unchanged
+	embed := &disgord.Embed{Title: "title", Description: "description"}
+	if got := FitEmbed(embed); got != embed {
+		t.Fatalf("got %v, want the same embed", got)
+	}
+
+	// Each part truncated to its limit, extra fields dropped
+	embed = &disgord.Embed{
+		Title:       strings.Repeat("т", 300),
+		Description: "description",
+		Fields:      makeFields(26, "n", "v"),
+		Footer:      &disgord.EmbedFooter{Text: "footer"},
+		Author:      &disgord.EmbedAuthor{Name: strings.Repeat("a", 300)},
+	}
+	want := &disgord.Embed{
+		Title:       strings.Repeat("т", 255) + "…",
+		Description: "description",
+		Fields:      makeFields(25, "n", "v"),
+		Footer:      &disgord.EmbedFooter{Text: "footer"},
+		Author:      &disgord.EmbedAuthor{Name: strings.Repeat("a", 255) + "…"},
+	}
+	if diff := deep.Equal(FitEmbed(embed), want); diff != nil {
+		t.Fatal(diff)
+	}
+	// The original is not modified
+	if len(embed.Fields) != 26 || utf8.RuneCountInString(embed.Title) != 300 {
+		t.Fatal("embed modified")
+	}
+
+	// Too long in total, description truncated first, then the last field values
+	embed = &disgord.Embed{
+		Title:       "title",                                          // 5
+		Description: strings.Repeat("a", 2000),                        // 2000
+		Fields:      makeFields(6, "name", strings.Repeat("b", 1000)), // 6 * 1004
+	}
+	got := FitEmbed(embed)
+	if err := ValidateEmbed(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Description != "" || got.Title != "title" {
+		t.Fatalf("got description %q and title %q, want description truncated first",
+			got.Description, got.Title)
+	}
+	for i, field := range got.Fields[:5] {
+		if field.Value != embed.Fields[i].Value {
+			t.Fatalf("field %v value truncated, want only the last truncated", i+1)
+		}
+	}
+	lastLen, wantLastLen := utf8.RuneCountInString(got.Fields[5].Value), 6000-5-6*4-5*1000
+	if lastLen != wantLastLen {
+		t.Fatalf("got last field value of %v chars, want %v", lastLen, wantLastLen)
+	}
+
+	// Markdown is not broken by the cut
+	embed = &disgord.Embed{
+		Description: "Some **bold words** and code:\n```cpp\n" + strings.Repeat("int x;\n", 400) + "```",
+	}
+	got = FitEmbed(embed)
+	if err := ValidateEmbed(got); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(got.Description, "…\n```") {
+		t.Fatalf("got description ending %q, want the code block closed",
+			got.Description[len(got.Description)-20:])
+	}
+}
+
+func TestFitContent(t *testing.T) {
+	content := strings.Repeat("я", 2000)
+	if got := FitContent(content); got != content {
+		t.Fatal("content within limit modified")
+	}
+	want := strings.Repeat("я", 1999) + "…"
+	if got := FitContent(content + "я"); got != want {
+		t.Fatalf("got %v chars, want %v", utf8.RuneCountInString(got), 2000)
+	}
+}
@@ -0,0 +1,357 @@
+package bot
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Delimiters of the inline markdown constructs that are closed when truncating. Longer delimiters
+// come first so that "**" is not read as two "*".
+var mdInlineDelims = []string{"||", "**", "__", "~~", "*", "_"}
+
+const mdCodeFence = "```"
+
+// The kinds of places where markdown text can be cut, from least to most preferred.
+const (
+	mdCutChar = iota
+	mdCutWord
+	mdCutBlock   // Between paragraphs
+	mdCutSection // Before a heading
+)
+
+// A place where markdown text can be cut.
+type mdCut struct {
+	pos   int // Byte offset
+	chars int // Number of chars before pos
+	kind  int
+	open  []string // Opening delimiters of the constructs open at pos, with the language for code
+}
+
+// Returns the markup that closes the open constructs.
+func (c *mdCut) closers() string {
+	var b strings.Builder
+	for i := len(c.open) - 1; i >= 0; i-- {
+		if strings.HasPrefix(c.open[i], mdCodeFence) {
+			b.WriteString("\n" + mdCodeFence)
+		} else {
+			b.WriteString(c.open[i])
+		}
+	}
+	return b.String()
+}
+
+// Returns the markup that opens the open constructs again.
+func (c *mdCut) reopeners() string {
+	return strings.Join(c.open, "")
+}
+
+// Returns whether the cut is in a spoiler or code block, which are better kept whole.
+func (c *mdCut) inBlock() bool {
+	for _, delim := range c.open {
+		if delim == "||" || strings.HasPrefix(delim, mdCodeFence) {
+			return true
+		}
+	}
+	return false
+}
+
+// TruncateMarkdown returns s unchanged if it has at most limit chars, otherwise returns it
+// truncated to at most limit chars. The text is cut at a paragraph or word boundary where possible,
+// never inside a link or a character, and not inside a spoiler or code block unless that would
+// leave too little. Constructs left open at the cut, such as bold text or code blocks, are closed.
+// Returns "" if there is nowhere to cut.
+func TruncateMarkdown(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	const ellipsis = "…"
+	cut := bestMarkdownCut(s, limit, ellipsis)
+	if cut == nil {
+		return ""
+	}
+	return strings.TrimRightFunc(s[:cut.pos], unicode.IsSpace) + ellipsis + cut.closers()
+}
+
+// SplitMarkdown splits s into parts of at most limit chars, cutting like TruncateMarkdown but
+// preferring to cut before headings. Constructs open at a cut are closed at the end of one part and
+// opened again at the start of the next. A part may exceed the limit only if it has nowhere to cut.
+func SplitMarkdown(s string, limit int) []string {
+	var parts []string
+	reopened := 0
+	for utf8.RuneCountInString(s) > limit {
+		cut := bestMarkdownCut(s, limit, "")
+		if cut == nil || cut.pos <= reopened {
+			break
+		}
+		parts = append(parts, strings.TrimRightFunc(s[:cut.pos], unicode.IsSpace)+cut.closers())
+		rest := s[cut.pos:]
+		if len(cut.open) == 0 || !strings.HasPrefix(cut.open[len(cut.open)-1], mdCodeFence) {
+			// Whitespace matters in code, the cut is before a newline there.
+			rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		}
+		s = cut.reopeners() + rest
+		reopened = len(cut.reopeners())
+	}
+	if s = strings.TrimRightFunc(s, unicode.IsSpace); s != "" {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+// Returns the best cut of s such that the text before it followed by the suffix and closers has at
+// most limit chars, or nil if there is none.
+func bestMarkdownCut(s string, limit int, suffix string) *mdCut {
+	// Cuts that leave at least half are preferred, then those not in spoilers or code blocks, then
+	// those at better boundaries, then those that leave more.
+	rank := func(cut *mdCut) [3]int {
+		switch {
+		case cut.chars < limit/2:
+			return [3]int{0, 0, cut.pos}
+		case cut.inBlock():
+			return [3]int{1, cut.kind, cut.pos}
+		}
+		return [3]int{2, cut.kind, cut.pos}
+	}
+	less := func(a, b [3]int) bool {
+		for i := range a {
+			if a[i] != b[i] {
+				return a[i] < b[i]
+			}
+		}
+		return false
+	}
+
+	var best *mdCut
+	for _, cut := range findMarkdownCuts(s, limit) {
+		cut := cut
+		text := strings.TrimRightFunc(s[:cut.pos], unicode.IsSpace) + suffix + cut.closers()
+		if utf8.RuneCountInString(text) > limit {
+			continue
+		}
+		if best == nil || less(rank(best), rank(&cut)) {
+			best = &cut
+		}
+	}
+	return best
+}
+
+// Returns the places where s can be cut leaving at most limit chars, in order.
+func findMarkdownCuts(s string, limit int) []mdCut {
+	var cuts []mdCut
+	var open []string
+	top := func() string {
+		if len(open) == 0 {
+			return ""
+		}
+		return open[len(open)-1]
+	}
+	addCut := func(pos, chars, kind int) {
+		cuts = append(cuts, mdCut{pos, chars, kind, append([]string(nil), open...)})
+	}
+	isWordRune := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	chars := 0
+	for i := 0; i < len(s) && chars <= limit; {
+		// Advances past n bytes of s, counting chars.
+		advance := func(n int) {
+			chars += utf8.RuneCountInString(s[i : i+n])
+			i += n
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case strings.HasPrefix(top(), mdCodeFence):
+			if strings.HasPrefix(s[i:], mdCodeFence) {
+				open = open[:len(open)-1]
+				advance(len(mdCodeFence))
+				continue
+			}
+			kind := mdCutChar
+			if s[i] == '\n' {
+				kind = mdCutWord
+			}
+			addCut(i, chars, kind)
+			advance(size)
+			continue
+		case top() == "`":
+			if s[i] == '`' {
+				open = open[:len(open)-1]
+				advance(1)
+				continue
+			}
+			addCut(i, chars, mdCutChar)
+			advance(size)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(s[i:], "\n\n"):
+			kind := mdCutBlock
+			if strings.HasPrefix(strings.TrimLeft(s[i:], "\n"), "#") {
+				kind = mdCutSection
+			}
+			addCut(i, chars, kind)
+			advance(2)
+		case unicode.IsSpace(r):
+			addCut(i, chars, mdCutWord)
+			advance(size)
+		case s[i] == '\\' && i+1 < len(s):
+			// Escaped char
+			addCut(i, chars, mdCutChar)
+			_, escSize := utf8.DecodeRuneInString(s[i+1:])
+			advance(1 + escSize)
+		case strings.HasPrefix(s[i:], mdCodeFence):
+			addCut(i, chars, mdCutChar)
+			// The language, if any, is part of the opening line.
+			end := strings.IndexByte(s[i:], '\n')
+			if end == -1 {
+				end = len(s) - i
+			}
+			open = append(open, s[i:i+end])
+			advance(end)
+		case s[i] == '`':
+			addCut(i, chars, mdCutChar)
+			open = append(open, "`")
+			advance(1)
+		case s[i] == '[' && linkLen(s[i:]) > 0:
+			// Links are kept whole.
+			addCut(i, chars, mdCutChar)
+			advance(linkLen(s[i:]))
+		default:
+			delim := ""
+			for _, d := range mdInlineDelims {
+				if strings.HasPrefix(s[i:], d) {
+					delim = d
+					break
+				}
+			}
+			if delim != "" && top() == delim {
+				open = open[:len(open)-1]
+				advance(len(delim))
+				break
+			}
+			prev, _ := utf8.DecodeLastRuneInString(s[:i])
+			next, _ := utf8.DecodeRuneInString(s[i+len(delim):])
+			// An opening delimiter must be followed by text, and a single _ inside a word, as in
+			// snake_case, is not emphasis.
+			if delim == "" || next == utf8.RuneError || unicode.IsSpace(next) ||
+				delim == "_" && isWordRune(prev) && isWordRune(next) {
+				addCut(i, chars, mdCutChar)
+				advance(size)
+				break
+			}
+			addCut(i, chars, mdCutChar)
+			open = append(open, delim)
+			advance(len(delim))
+		}
+	}
+	return cuts
+}
+
+// MarkdownTokenKind is the kind of a MarkdownToken.
+type MarkdownTokenKind int
+
+// Kinds of markdown tokens.
+const (
+	MarkdownWord      MarkdownTokenKind = iota // Text up to whitespace or another token
+	MarkdownCode                               // An inline code span
+	MarkdownCodeBlock                          // A fenced code block, with the fences
+	MarkdownLink                               // A link [text](url) or image ![text](url)
+)
+
+// MarkdownToken is a token of markdown text at s[Start:End].
+type MarkdownToken struct {
+	Kind       MarkdownTokenKind
+	Start, End int
+}
+
+// TokenizeMarkdown splits s into words, code spans, code blocks and links, which are kept whole.
+// The whitespace between tokens is not part of any token. Inline formatting delimiters are part of
+// the words they are attached to.
+func TokenizeMarkdown(s string) []MarkdownToken {
+	var tokens []MarkdownToken
+	// Returns the length of the link at i, including the ! of an image, or 0 if there is none.
+	linkAt := func(i int) int {
+		if s[i] == '!' && i+1 < len(s) && s[i+1] == '[' {
+			if n := linkLen(s[i+1:]); n > 0 {
+				return n + 1
+			}
+		}
+		if s[i] == '[' {
+			return linkLen(s[i:])
+		}
+		return 0
+	}
+	// Returns the length of the code span or block at i, or 0 if there is none. Unclosed code
+	// blocks go on to the end.
+	codeAt := func(i int) (int, MarkdownTokenKind) {
+		if strings.HasPrefix(s[i:], mdCodeFence) {
+			end := strings.Index(s[i+len(mdCodeFence):], mdCodeFence)
+			if end == -1 {
+				return len(s) - i, MarkdownCodeBlock
+			}
+			return end + 2*len(mdCodeFence), MarkdownCodeBlock
+		}
+		if s[i] == '`' {
+			if end := strings.IndexByte(s[i+1:], '`'); end != -1 {
+				return end + 2, MarkdownCode
+			}
+		}
+		return 0, MarkdownWord
+	}
+
+	wordStart := -1
+	endWord := func(i int) {
+		if wordStart != -1 {
+			tokens = append(tokens, MarkdownToken{MarkdownWord, wordStart, i})
+			wordStart = -1
+		}
+	}
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsSpace(r) {
+			endWord(i)
+			i += size
+			continue
+		}
+		if n, kind := codeAt(i); n > 0 {
+			endWord(i)
+			tokens = append(tokens, MarkdownToken{kind, i, i + n})
+			i += n
+			continue
+		}
+		if n := linkAt(i); n > 0 {
+			endWord(i)
+			tokens = append(tokens, MarkdownToken{MarkdownLink, i, i + n})
+			i += n
+			continue
+		}
+		if wordStart == -1 {
+			wordStart = i
+		}
+		if s[i] == '\\' && i+1 < len(s) {
+			// Escaped char
+			_, escSize := utf8.DecodeRuneInString(s[i+1:])
+			size = 1 + escSize
+		}
+		i += size
+	}
+	endWord(len(s))
+	return tokens
+}
+
+// Returns the length of the markdown link [text](url) at the start of s, or 0 if there is none.
+func linkLen(s string) int {
+	textEnd := strings.Index(s, "](")
+	if textEnd == -1 || strings.ContainsRune(s[:textEnd], '\n') {
+		return 0
+	}
+	urlEnd := strings.IndexAny(s[textEnd+2:], ")\n")
+	if urlEnd == -1 || s[textEnd+2+urlEnd] != ')' {
+		return 0
+	}
+	return textEnd + 2 + urlEnd + 1
+}
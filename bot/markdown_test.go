@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-test/deep"
+)
+
+func TestTruncateMarkdown(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		s     string
+		limit int
+		want  string
+	}{
+		{"short", "hello **world**", 20, "hello **world**"},
+		{"word boundary", "hello wonderful world", 18, "hello wonderful…"},
+		{
+			"paragraph boundary",
+			"first paragraph here\n\nsecond paragraph here",
+			35,
+			"first paragraph here…",
+		},
+		{"cyrillic", "привет прекрасный мир", 18, "привет прекрасный…"},
+		{"no spaces", strings.Repeat("я", 30), 10, strings.Repeat("я", 9) + "…"},
+		{"bold", "some **bold text that goes on**", 20, "some **bold text…**"},
+		{"nested", "a ~~struck *and italic text*~~", 26, "a ~~struck *and italic…*~~"},
+		{"snake_case", "call some_function_name here", 24, "call some_function_name…"},
+		{"lone asterisk", "a * b * c d e f g h", 12, "a * b * c d…"},
+		{"escaped", `a \*not bold and more words`, 20, `a \*not bold and…`},
+		{"inline code", "run `go test ./... -race` now", 20, "run `go test ./...…`"},
+		{
+			"code block",
+			"code:\n```cpp\nint main() {\n  return 0;\n}\n```",
+			30,
+			"code:\n```cpp\nint main() {…\n```",
+		},
+		{
+			"link kept whole",
+			"see [this blog](https://codeforces.com/blog/entry/1) please",
+			40,
+			"see…",
+		},
+		{
+			"link fits",
+			"see [blog](https://codeforces.com/blog/entry/1) for details",
+			55,
+			"see [blog](https://codeforces.com/blog/entry/1) for…",
+		},
+		{
+			"spoiler kept intact",
+			"the answer is below\n\n||it is forty two, obviously||",
+			35,
+			"the answer is below…",
+		},
+		{
+			"spoiler cut if needed",
+			"hint\n\n||it is forty two, obviously, and nothing else||",
+			38,
+			"hint\n\n||it is forty two, obviously,…||",
+		},
+	} {
+		got := TruncateMarkdown(test.s, test.limit)
+		if got != test.want {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+		if n := utf8.RuneCountInString(got); n > test.limit {
+			t.Errorf("%v: got %v chars, limit %v", test.name, n, test.limit)
+		}
+	}
+}
+
+func TestSplitMarkdown(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		s     string
+		limit int
+		want  []string
+	}{
+		{"short", "hello world", 20, []string{"hello world"}},
+		{
+			"paragraphs",
+			"first paragraph\n\nsecond paragraph\n\nthird paragraph",
+			36,
+			[]string{"first paragraph\n\nsecond paragraph", "third paragraph"},
+		},
+		{
+			"sections",
+			"an introduction of some length\n\n# Part one\n\nsome text\n\nmore text",
+			50,
+			[]string{"an introduction of some length", "# Part one\n\nsome text\n\nmore text"},
+		},
+		{
+			"code block",
+			"```cpp\nint a;\nint b;\nint c;\n```",
+			20,
+			[]string{"```cpp\nint a;\n```", "```cpp\nint b;\n```", "```cpp\nint c;\n```"},
+		},
+		{
+			"spoiler",
+			"||one two three four five six||",
+			20,
+			[]string{"||one two three||", "||four five six||"},
+		},
+	} {
+		got := SplitMarkdown(test.s, test.limit)
+		if diff := deep.Equal(got, test.want); diff != nil {
+			t.Errorf("%v: %v", test.name, diff)
+		}
+		for _, part := range got {
+			if n := utf8.RuneCountInString(part); n > test.limit {
+				t.Errorf("%v: got part of %v chars, limit %v", test.name, n, test.limit)
+			}
+		}
+	}
+}
+
+func TestTokenizeMarkdown(t *testing.T) {
+	s := "a **b** `c d` [e f](https://g.h) ![i](j) x\\`y\n```cpp\nint k;\n```\n`unclosed"
+	var got []string
+	for _, token := range TokenizeMarkdown(s) {
+		got = append(got, fmt.Sprintf("%v:%v", token.Kind, s[token.Start:token.End]))
+	}
+	want := []string{
+		"0:a",
+		"0:**b**",
+		"1:`c d`",
+		"3:[e f](https://g.h)",
+		"3:![i](j)",
+		"0:x\\`y",
+		"2:```cpp\nint k;\n```",
+		"0:`unclosed",
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Fatal(diff)
+	}
+}
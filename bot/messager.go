@@ -19,6 +19,7 @@ type Messager interface {
 	UnreactUser(ctx context.Context, msg *disgord.Message, reaction string, userID disgord.Snowflake) error
 	Delete(ctx context.Context, msg *disgord.Message) error
 	AddReactListener(filter disgord.Middleware, ctrl disgord.HandlerCtrl, handler disgord.HandlerMessageReactionAdd)
+	AddReplyListener(filter disgord.Middleware, ctrl disgord.HandlerCtrl, handler disgord.HandlerMessageCreate)
 }
 
 type disgordMessager struct {
@@ -88,4 +89,15 @@ func (m *disgordMessager) AddReactListener(
 	m.session.Gateway().WithMiddleware(filter).WithCtrl(ctrl).MessageReactionAdd(handler)
 }
 
+func (m *disgordMessager) AddReplyListener(
+	filter disgord.Middleware,
+	ctrl disgord.HandlerCtrl,
+	handler disgord.HandlerMessageCreate,
+) {
+	m.session.Gateway().
+		WithMiddleware(filterMsgCreateNotBot, filter).
+		WithCtrl(ctrl).
+		MessageCreate(handler)
+}
+
 var _ Messager = (*disgordMessager)(nil)
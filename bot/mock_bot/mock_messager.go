@@ -48,6 +48,18 @@ func (mr *MockMessagerMockRecorder) AddReactListener(arg0, arg1, arg2 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReactListener", reflect.TypeOf((*MockMessager)(nil).AddReactListener), arg0, arg1, arg2)
 }
 
+// AddReplyListener mocks base method.
+func (m *MockMessager) AddReplyListener(arg0 func(interface{}) interface{}, arg1 disgord.HandlerCtrl, arg2 func(disgord.Session, *disgord.MessageCreate)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddReplyListener", arg0, arg1, arg2)
+}
+
+// AddReplyListener indicates an expected call of AddReplyListener.
+func (mr *MockMessagerMockRecorder) AddReplyListener(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReplyListener", reflect.TypeOf((*MockMessager)(nil).AddReplyListener), arg0, arg1, arg2)
+}
+
 // Delete mocks base method.
 func (m *MockMessager) Delete(arg0 context.Context, arg1 *disgord.Message) error {
 	m.ctrl.T.Helper()
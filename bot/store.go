@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord"
+)
+
+// WidgetState is the state of a widget that is persisted so that the widget can be resumed or
+// cleaned up after a restart.
+type WidgetState struct {
+	ChannelID disgord.Snowflake
+	MessageID disgord.Snowflake
+
+	// Identifies the resumer and what it should rebuild the pages from, see Bot.AddWidgetResumer.
+	Kind   string
+	Source string
+
+	// The message that triggered the widget, and its guild if not in a DM.
+	GuildID          disgord.Snowflake
+	TriggerMessageID disgord.Snowflake
+	TriggerAuthorID  disgord.Snowflake
+
+	PageNum   int
+	Expanded  bool
+	Reacts    []string
+	Expiry    time.Time
+	MaxExpiry time.Time
+}
+
+// WidgetStore persists widget states.
+type WidgetStore interface {
+	Save(state *WidgetState) error
+	Remove(messageID disgord.Snowflake) error
+	LoadAll() ([]*WidgetState, error)
+}
+
+// FileWidgetStore is a WidgetStore that keeps all widget states in a JSON file. It is safe for
+// concurrent use.
+type FileWidgetStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileWidgetStore returns a FileWidgetStore backed by the file at the given path. The file is
+// created when a state is first saved.
+func NewFileWidgetStore(path string) *FileWidgetStore {
+	return &FileWidgetStore{path: path}
+}
+
+// Save saves the state, replacing any existing state for the same message.
+func (s *FileWidgetStore) Save(state *WidgetState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.read()
+	if err != nil {
+		return err
+	}
+	for i, st := range states {
+		if st.MessageID == state.MessageID {
+			states = append(states[:i], states[i+1:]...)
+			break
+		}
+	}
+	return s.write(append(states, state))
+}
+
+// Remove removes the state for the given message, if present.
+func (s *FileWidgetStore) Remove(messageID disgord.Snowflake) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.read()
+	if err != nil {
+		return err
+	}
+	for i, st := range states {
+		if st.MessageID == messageID {
+			return s.write(append(states[:i], states[i+1:]...))
+		}
+	}
+	return nil
+}
+
+// LoadAll returns all saved states, ordered by message ID.
+func (s *FileWidgetStore) LoadAll() ([]*WidgetState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].MessageID < states[j].MessageID })
+	return states, nil
+}
+
+func (s *FileWidgetStore) read() ([]*WidgetState, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var states []*WidgetState
+	if err = json.Unmarshal(data, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (s *FileWidgetStore) write(states []*WidgetState) error {
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename so that a crash midway doesn't corrupt the store.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+var _ WidgetStore = (*FileWidgetStore)(nil)
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestFileWidgetStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "widgetstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "widgets.json")
+
+	expiry := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	state1 := &WidgetState{
+		ChannelID:        testChannelID,
+		MessageID:        823564,
+		Kind:             "blog",
+		Source:           "https://codeforces.com/blog/entry/80540",
+		GuildID:          6543217,
+		TriggerMessageID: 812345,
+		TriggerAuthorID:  testUserID,
+		PageNum:          1,
+		Reacts:           []string{delSymbol},
+		Expiry:           expiry,
+	}
+	state2 := &WidgetState{
+		ChannelID: testChannelID,
+		MessageID: 823563,
+		Kind:      "submission",
+		PageNum:   2,
+		Expanded:  true,
+		Reacts:    []string{delSymbol, lessSymbol},
+		Expiry:    expiry,
+	}
+
+	store := NewFileWidgetStore(path)
+	check := func(want []*WidgetState) {
+		// A new store reads what the old one wrote.
+		got, err := NewFileWidgetStore(path).LoadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(got, want); diff != nil {
+			t.Fatal(diff)
+		}
+	}
+
+	check(nil)
+	if err = store.Save(state1); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Save(state2); err != nil {
+		t.Fatal(err)
+	}
+	check([]*WidgetState{state2, state1})
+
+	state1.PageNum = 3
+	if err = store.Save(state1); err != nil {
+		t.Fatal(err)
+	}
+	check([]*WidgetState{state2, state1})
+
+	if err = store.Remove(state2.MessageID); err != nil {
+		t.Fatal(err)
+	}
+	check([]*WidgetState{state1})
+
+	if err = store.Remove(834512); err != nil {
+		t.Fatal(err)
+	}
+	check([]*WidgetState{state1})
+}
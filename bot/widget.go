@@ -1,10 +1,15 @@
 package bot
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/andersfylling/disgord"
@@ -23,14 +28,55 @@ type Page struct {
 
 	// The expanded message for this page, optional.
 	Expanded *Message
+
+	// Optional, loads the expanded message when the page is first expanded, for expansions that
+	// take a while to get. Only used if Expanded is not set. The loading page is shown meanwhile,
+	// and the error page if loading fails, in which case it is loaded again on the next expand.
+	LoadExpanded func(ctx context.Context) (*Message, error)
+
+	// Files attached to the page, optional. Discord does not allow changing the attachments of a
+	// message, so the widget message is replaced by a new one when moving to or from a page with
+	// files.
+	Files []*File
+}
+
+// File is a file attached to a page. Unlike disgord.CreateMessageFileParams it holds the data and
+// not a reader, so that it can be sent every time the page is shown.
+type File struct {
+	Name string
+	Data []byte
+}
+
+func (p *Page) hasExpansion() bool {
+	return p.Expanded != nil || p.LoadExpanded != nil
 }
 
 // Pages is a set of pages, numbered 1 to Total. First is shown first.
 type Pages struct {
-	Get   func(pageNum int) *Page
+	// At least one of Get and Load must be set. Get is called while the widget is busy so it should
+	// be fast. Load is meant for pages that take a while to get, see below. If both are set, Get
+	// returns nil for the pages to get with Load.
+	Get  func(pageNum int) *Page
+	Load func(ctx context.Context, pageNum int) (*Page, error)
+
 	Total int
 	First int
+
+	// Files attached to the message when it is first sent. These are lost if the message is
+	// replaced, use Page.Files for files that should stay.
 	Files []disgord.CreateMessageFileParams
+
+	// Optional, the page shown while a page is loaded with Load. Defaults to a plain loading
+	// message. Loaded pages are cached and pages next to the one shown are loaded in advance.
+	Loading *Page
+
+	// Optional, returns the page to show when Load fails. Defaults to a page with the error
+	// message. Failed pages are loaded again the next time they are shown.
+	ErrorPage func(pageNum int, err error) *Page
+
+	// Optional, returns the text of a page indicator to be appended to the footer of page embeds.
+	// Only used if there is more than one page. PageIndicator is a simple choice.
+	Indicator func(pageNum, total int) string
 }
 
 // MsgCallbackType is the callback function type invoked on message create.
@@ -39,8 +85,18 @@ type MsgCallbackType func(*disgord.Message)
 // DelCallbackType is the callback function type invoked on delete.
 type DelCallbackType func(*disgord.MessageReactionAdd)
 
+// WidgetOp is an operation that can be performed on a widget.
+type WidgetOp int
+
+// Widget operations.
+const (
+	OpDelete   WidgetOp = iota
+	OpNavigate          // Changing, expanding or contracting the page
+)
+
 // AllowPredicateType is the predicate type that returns whether the operation on react is allowed.
-type AllowPredicateType func(*disgord.MessageReactionAdd) bool
+// Jumping to a page by replying is checked with an event that has the replying user and no emoji.
+type AllowPredicateType func(op WidgetOp, evt *disgord.MessageReactionAdd) bool
 
 // WidgetParams aggregates the params required for a paginated widget.
 type WidgetParams struct {
@@ -52,28 +108,57 @@ type WidgetParams struct {
 	// After this duration the message will not be monitored.
 	Lifetime time.Duration
 
+	// Optional. If more than Lifetime, every operation extends the life of the widget to Lifetime
+	// from then on, up to MaxLifetime from when the widget was created.
+	MaxLifetime time.Duration
+
 	// Optional callback to be invoked when the message is deleted.
 	DelCallback DelCallbackType
 
-	// Optional check called before performing any operation (delete, prev, next). Defaults to
-	// always allowed.
+	// Optional check called before performing any operation. Defaults to always allowed.
 	AllowOp AllowPredicateType
+
+	// Optional, called in the background once the widget message is sent, for pages that change
+	// over time. update replaces the page with the given number, showing it if it is the current
+	// page. Refresh should return when ctx is done, which is when the widget expires.
+	Refresh func(ctx context.Context, update func(pageNum int, page *Page))
+
+	// Optional, used to persist the widget when sent with Context.SendWidget and the bot has a
+	// WidgetStore. After a restart the resumer added for Kind rebuilds the pages from Source,
+	// which is typically the URL the widget was created for.
+	Kind   string
+	Source string
 }
 
 const (
-	delSymbol  = "🗑"
-	prevSymbol = "◀"
-	nextSymbol = "▶"
-	moreSymbol = "🔽"
-	lessSymbol = "🔼"
+	delSymbol   = "🗑"
+	firstSymbol = "⏮"
+	prevSymbol  = "◀"
+	nextSymbol  = "▶"
+	lastSymbol  = "⏭"
+	moreSymbol  = "🔽"
+	lessSymbol  = "🔼"
 )
 
 var allSymbols = map[string]bool{
-	delSymbol:  true,
-	prevSymbol: true,
-	nextSymbol: true,
-	moreSymbol: true,
-	lessSymbol: true,
+	delSymbol:   true,
+	firstSymbol: true,
+	prevSymbol:  true,
+	nextSymbol:  true,
+	lastSymbol:  true,
+	moreSymbol:  true,
+	lessSymbol:  true,
+}
+
+var defaultLoadingPage = NewPage("", &disgord.Embed{Description: "Loading…"})
+
+// First and last controls, and jumping to a page by replying, are only offered beyond this many
+// pages. With fewer pages previous and next suffice.
+const jumpMinPages = 3
+
+// PageIndicator returns a page indicator like "Page 2/5".
+func PageIndicator(pageNum, total int) string {
+	return fmt.Sprintf("Page %v/%v", pageNum, total)
 }
 
 // NewPage returns a Page with a default message and no expanded message.
@@ -125,19 +210,49 @@ type widget struct {
 	messager Messager
 	logger   disgord.Logger
 
+	// Optional, where to persist the widget state. state holds the persisted fields that don't
+	// change over the widget's life.
+	store WidgetStore
+	state *WidgetState
+
 	// The active context for the widget
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	// The widget message
-	msg *disgord.Message
+	// When the widget stops being monitored and how far that can be extended
+	expiry    time.Time
+	maxExpiry time.Time
+	timer     *time.Timer
+	expired   int32 // Set atomically when the timer fires
 
 	// The current state of the widget
 	sync.Mutex
+	channelID      disgord.Snowflake
+	msg            *disgord.Message
+	msgFiles       []*File
+	ctrl           *manualCtrl
 	currentPageNum int
 	currentPage    *Page
 	expanded       bool
 	currentReacts  map[string]bool
+	loads          map[int]*pageLoad
+	expansions     map[*Page]*expansionLoad
+	refreshed      map[int]*Page // Pages replaced by Refresh
+}
+
+// A page being loaded with Pages.Load. page is set before done is closed, and err is set if loading
+// failed, in which case page is the error page.
+type pageLoad struct {
+	done chan struct{}
+	page *Page
+	err  error
+}
+
+// A page expansion being loaded with Page.LoadExpanded, like pageLoad.
+type expansionLoad struct {
+	done chan struct{}
+	msg  *Message
+	err  error
 }
 
 func (w *widget) run(ctx context.Context, channelID disgord.Snowflake) error {
@@ -146,61 +261,187 @@ func (w *widget) run(ctx context.Context, channelID disgord.Snowflake) error {
 	}
 
 	// Initialize context
-	w.ctx, w.cancel = context.WithTimeout(ctx, w.params.Lifetime)
+	now := time.Now()
+	w.startTimer(ctx, now.Add(w.params.Lifetime), now.Add(w.params.MaxLifetime))
 	defer w.cancel()
 
 	// Initialize state
+	w.channelID = channelID
 	w.currentPageNum = w.params.Pages.First
-	w.currentPage = w.params.Pages.Get(w.currentPageNum)
 	w.expanded = false
 	w.currentReacts = make(map[string]bool)
+	w.loads = make(map[int]*pageLoad)
+	w.expansions = make(map[*Page]*expansionLoad)
+	w.refreshed = make(map[int]*Page)
+	var err error
+	if w.currentPage, err = w.waitForPage(w.currentPageNum); err != nil {
+		return err
+	}
 
 	// Send page to show first, add reacts
+	content, embed := w.render(w.currentPage.Default)
+	files := w.params.Pages.Files
+	if len(w.currentPage.Files) > 0 {
+		files = append(append([]disgord.CreateMessageFileParams{}, files...),
+			makeFileParams(w.currentPage.Files)...)
+	}
 	params := &disgord.CreateMessageParams{
-		Content: w.currentPage.Default.Content,
-		Embed:   w.currentPage.Default.Embed,
-		Files:   w.params.Pages.Files,
+		Content: content,
+		Embed:   embed,
+		Files:   files,
 	}
-	var err error
 	w.msg, err = w.messager.Send(w.ctx, channelID, params)
 	if err != nil {
 		return err
 	}
+	w.msgFiles = w.currentPage.Files
 	w.params.MsgCallback(w.msg)
-	w.reactOnMsg(delSymbol)
-	if w.params.Pages.Total > 1 {
-		w.reactOnMsg(prevSymbol)
-		w.reactOnMsg(nextSymbol)
+	w.addMissingControlReacts()
+	w.fixMoreLessReactsForCurrentPage()
+	if w.state != nil {
+		w.state.ChannelID = channelID
+	}
+	w.persist()
+	w.prefetchAround(w.currentPageNum)
+	w.startRefresh()
+
+	return w.listen(ctx)
+}
+
+// Takes control of an existing widget message described by the persisted state. The page shown is
+// refreshed and the widget is then monitored until the persisted expiry.
+func (w *widget) resume(ctx context.Context, state *WidgetState) error {
+	if err := w.validateAndUpdateParams(); err != nil {
+		return err
+	}
+
+	// Initialize context
+	w.startTimer(ctx, state.Expiry, state.MaxExpiry)
+	defer w.cancel()
+
+	// Initialize state
+	w.state = state
+	w.channelID = state.ChannelID
+	w.msg = &disgord.Message{ID: state.MessageID, ChannelID: state.ChannelID}
+	w.currentPageNum = state.PageNum
+	if w.currentPageNum < 1 || w.currentPageNum > w.params.Pages.Total {
+		w.currentPageNum = w.params.Pages.First
+	}
+	w.currentReacts = make(map[string]bool)
+	for _, react := range state.Reacts {
+		w.currentReacts[react] = true
+	}
+	w.loads = make(map[int]*pageLoad)
+	w.expansions = make(map[*Page]*expansionLoad)
+	w.refreshed = make(map[int]*Page)
+	var err error
+	if w.currentPage, err = w.waitForPage(w.currentPageNum); err != nil {
+		return err
+	}
+	// Expansions that are loaded are shown contracted, rather than loading them first.
+	w.expanded = state.Expanded && w.currentPage.Expanded != nil
+	// The message was sent with the files of the page, assuming they haven't changed.
+	w.msgFiles = w.currentPage.Files
+
+	// Show the refreshed page, fix reacts in case the number of pages changed
+	msg := w.currentPage.Default
+	if w.expanded {
+		msg = w.currentPage.Expanded
+	}
+	content, embed := w.render(msg)
+	if _, err := w.messager.Edit(w.ctx, w.msg, content, embed); err != nil {
+		return err
 	}
+	w.addMissingControlReacts()
 	w.fixMoreLessReactsForCurrentPage()
+	w.persist()
+	w.prefetchAround(w.currentPageNum)
+	w.startRefresh()
 
-	// Listen for reacts on the message
-	var ctrl manualCtrl
+	return w.listen(ctx)
+}
+
+// Listens for reacts and replies on the widget message until the widget's context is done.
+func (w *widget) listen(ctx context.Context) error {
+	w.Lock()
+	w.addListeners()
+	w.Unlock()
+
+	<-w.ctx.Done()
+	w.Lock()
+	defer w.Unlock()
+	w.ctrl.kill()
+	if atomic.LoadInt32(&w.expired) == 1 && ctx.Err() == nil {
+		w.cleanupReacts(ctx)
+		w.unpersist()
+		return nil
+	}
+	// If the parent context is done the widget is left persisted, to be resumed later.
+	return ctx.Err()
+}
+
+// Adds listeners for reacts and replies on the current widget message.
+func (w *widget) addListeners() {
+	w.ctrl = &manualCtrl{}
 	w.messager.AddReactListener(
 		filterReactionAddForMsg(w.msg.ID),
-		&ctrl,
+		w.ctrl,
 		func(_ disgord.Session, evt *disgord.MessageReactionAdd) {
-			if allSymbols[evt.PartialEmoji.Name] && w.params.AllowOp(evt) {
+			react := evt.PartialEmoji.Name
+			op := OpNavigate
+			if react == delSymbol {
+				op = OpDelete
+			}
+			if allSymbols[react] && w.params.AllowOp(op, evt) {
 				go w.handleControlReact(evt)
 			}
 		},
 	)
+	if w.params.Pages.Total >= jumpMinPages {
+		w.messager.AddReplyListener(
+			filterMsgCreateReplyTo(w.msg.ID),
+			w.ctrl,
+			func(_ disgord.Session, evt *disgord.MessageCreate) {
+				go w.handleReply(evt.Message)
+			},
+		)
+	}
+}
 
-	<-w.ctx.Done()
-	ctrl.kill()
-	if w.ctx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
-		w.cleanupReacts(ctx)
-		return nil
+// Initializes the widget context, which is cancelled when the widget expires. Expiry can later be
+// extended up to maxExpiry.
+func (w *widget) startTimer(ctx context.Context, expiry, maxExpiry time.Time) {
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.expiry = expiry
+	w.maxExpiry = maxExpiry
+	w.timer = time.AfterFunc(time.Until(expiry), func() {
+		atomic.StoreInt32(&w.expired, 1)
+		w.cancel()
+	})
+}
+
+// Extends the life of the widget to Lifetime from now, if allowed by MaxLifetime.
+func (w *widget) extendLife() {
+	expiry := time.Now().Add(w.params.Lifetime)
+	if expiry.After(w.maxExpiry) {
+		expiry = w.maxExpiry
+	}
+	if !expiry.After(w.expiry) {
+		return
+	}
+	// If the timer has already fired the widget is done anyway.
+	if w.timer.Stop() {
+		w.expiry = expiry
+		w.timer.Reset(time.Until(expiry))
 	}
-	return ctx.Err()
 }
 
 func (w *widget) validateAndUpdateParams() error {
 	if w.params.Pages == nil {
 		return errors.New("Pages must not be nil")
 	}
-	if w.params.Pages.Get == nil {
-		return errors.New("Pages.Get must not be nil")
+	if w.params.Pages.Get == nil && w.params.Pages.Load == nil {
+		return errors.New("At least one of Pages.Get and Pages.Load must be set")
 	}
 	if w.params.Pages.Total < 1 {
 		return fmt.Errorf("Pages.Total must be positive, found %v", w.params.Pages.Total)
@@ -210,17 +451,28 @@ func (w *widget) validateAndUpdateParams() error {
 			"Pages.First must be between 1 and %v, found %v",
 			w.params.Pages.Total, w.params.Pages.First)
 	}
+	if w.params.Pages.Loading == nil {
+		w.params.Pages.Loading = defaultLoadingPage
+	}
+	if w.params.Pages.ErrorPage == nil {
+		w.params.Pages.ErrorPage = func(_ int, err error) *Page {
+			return NewPage("", &disgord.Embed{Description: err.Error()})
+		}
+	}
 	if w.params.MsgCallback == nil {
 		w.params.MsgCallback = func(*disgord.Message) {}
 	}
 	if w.params.Lifetime <= 0 {
 		return fmt.Errorf("Lifetime must be positive, found %v", w.params.Lifetime)
 	}
+	if w.params.MaxLifetime < w.params.Lifetime {
+		w.params.MaxLifetime = w.params.Lifetime
+	}
 	if w.params.DelCallback == nil {
 		w.params.DelCallback = func(*disgord.MessageReactionAdd) {}
 	}
 	if w.params.AllowOp == nil {
-		w.params.AllowOp = func(*disgord.MessageReactionAdd) bool { return true }
+		w.params.AllowOp = func(WidgetOp, *disgord.MessageReactionAdd) bool { return true }
 	}
 	return nil
 }
@@ -249,10 +501,102 @@ func (w *widget) cleanupReacts(ctx context.Context) {
 	}
 }
 
+// Saves the current state of the widget to the store, if there is one.
+func (w *widget) persist() {
+	if w.store == nil {
+		return
+	}
+	w.state.MessageID = w.msg.ID
+	w.state.PageNum = w.currentPageNum
+	w.state.Expanded = w.expanded
+	w.state.Reacts = w.state.Reacts[:0]
+	for react := range w.currentReacts {
+		w.state.Reacts = append(w.state.Reacts, react)
+	}
+	sort.Strings(w.state.Reacts)
+	w.state.Expiry = w.expiry
+	w.state.MaxExpiry = w.maxExpiry
+	if err := w.store.Save(w.state); err != nil {
+		w.logger.Error(fmt.Errorf("Save widget state failed: %w", err))
+	}
+}
+
+// Removes the widget state from the store, if there is one.
+func (w *widget) unpersist() {
+	if w.store == nil {
+		return
+	}
+	if err := w.store.Remove(w.msg.ID); err != nil {
+		w.logger.Error(fmt.Errorf("Remove widget state failed: %w", err))
+	}
+}
+
+// Removes the reacts of a persisted widget that cannot be resumed, and removes its state from the
+// store.
+func cleanupPersistedWidget(
+	ctx context.Context,
+	state *WidgetState,
+	store WidgetStore,
+	messager Messager,
+	logger disgord.Logger,
+) {
+	msg := &disgord.Message{ID: state.MessageID, ChannelID: state.ChannelID}
+	for _, react := range state.Reacts {
+		if err := messager.Unreact(ctx, msg, react); err != nil {
+			logger.Error(fmt.Errorf("Clean up react %q failed: %w", react, err))
+		}
+	}
+	if err := store.Remove(state.MessageID); err != nil {
+		logger.Error(fmt.Errorf("Remove widget state failed: %w", err))
+	}
+}
+
+// Returns the content and embed to show for the given message of the current page, with the page
+// indicator added to the embed footer if required. Both are truncated to fit Discord's limits if
+// they don't already, since Discord would reject them otherwise.
+func (w *widget) render(msg *Message) (string, *disgord.Embed) {
+	embed := msg.Embed
+	if w.params.Pages.Indicator != nil && w.params.Pages.Total > 1 && embed != nil {
+		indicator := w.params.Pages.Indicator(w.currentPageNum, w.params.Pages.Total)
+		// Copy only what is modified, the page belongs to the caller.
+		embedCopy := *embed
+		var footer disgord.EmbedFooter
+		if embedCopy.Footer != nil {
+			footer = *embedCopy.Footer
+		}
+		if footer.Text != "" {
+			footer.Text += "  •  "
+		}
+		footer.Text += indicator
+		embedCopy.Footer = &footer
+		embed = &embedCopy
+	}
+	if embed != nil {
+		embed = FitEmbed(embed)
+	}
+	return FitContent(msg.Content), embed
+}
+
+// Adds the delete and navigation reacts that are not already present.
+func (w *widget) addMissingControlReacts() {
+	reacts := []string{delSymbol}
+	switch total := w.params.Pages.Total; {
+	case total >= jumpMinPages:
+		reacts = append(reacts, firstSymbol, prevSymbol, nextSymbol, lastSymbol)
+	case total > 1:
+		reacts = append(reacts, prevSymbol, nextSymbol)
+	}
+	for _, react := range reacts {
+		if !w.currentReacts[react] {
+			w.reactOnMsg(react)
+		}
+	}
+}
+
 func (w *widget) fixMoreLessReactsForCurrentPage() {
 	reacts := []string{moreSymbol, lessSymbol}
 	want := make(map[string]bool)
-	if w.currentPage.Expanded != nil {
+	if w.currentPage.hasExpansion() {
 		if w.expanded {
 			want[lessSymbol] = true
 		} else {
@@ -273,12 +617,22 @@ func (w *widget) fixMoreLessReactsForCurrentPage() {
 }
 
 func (w *widget) expandCurrentPage() {
-	if w.currentPage.Expanded == nil || w.expanded {
+	if !w.currentPage.hasExpansion() || w.expanded {
 		return
 	}
-	_, err := w.messager.Edit(
-		w.ctx, w.msg, w.currentPage.Expanded.Content, w.currentPage.Expanded.Embed)
-	if err != nil {
+	msg := w.currentPage.Expanded
+	if msg == nil {
+		load := w.loadExpansion(w.currentPageNum, w.currentPage)
+		select {
+		case <-load.done:
+			msg = load.msg
+		default:
+			msg = w.params.Pages.Loading.Default
+			go w.showExpansionWhenLoaded(w.currentPage, load)
+		}
+	}
+	content, embed := w.render(msg)
+	if _, err := w.messager.Edit(w.ctx, w.msg, content, embed); err != nil {
 		w.logger.Error(fmt.Errorf("Failed to expand page: %w", err))
 		return
 	}
@@ -287,12 +641,11 @@ func (w *widget) expandCurrentPage() {
 }
 
 func (w *widget) contractCurrentPage() {
-	if w.currentPage.Expanded == nil || !w.expanded {
+	if !w.currentPage.hasExpansion() || !w.expanded {
 		return
 	}
-	_, err := w.messager.Edit(
-		w.ctx, w.msg, w.currentPage.Default.Content, w.currentPage.Default.Embed)
-	if err != nil {
+	content, embed := w.render(w.currentPage.Default)
+	if _, err := w.messager.Edit(w.ctx, w.msg, content, embed); err != nil {
 		w.logger.Error(fmt.Errorf("Failed to contract page: %w", err))
 		return
 	}
@@ -300,23 +653,230 @@ func (w *widget) contractCurrentPage() {
 	w.fixMoreLessReactsForCurrentPage()
 }
 
-func (w *widget) showPage(delta int) {
-	newPageNum := w.currentPageNum + delta
-	if newPageNum < 1 || newPageNum > w.params.Pages.Total {
+func (w *widget) showPage(newPageNum int) {
+	if newPageNum < 1 || newPageNum > w.params.Pages.Total || newPageNum == w.currentPageNum {
 		return
 	}
-	newPage := w.params.Pages.Get(newPageNum)
-	_, err := w.messager.Edit(w.ctx, w.msg, newPage.Default.Content, newPage.Default.Embed)
+	if page, ok := w.refreshed[newPageNum]; ok {
+		w.switchToPage(newPageNum, page)
+		return
+	}
+	if page := w.getPage(newPageNum); page != nil {
+		w.switchToPage(newPageNum, page)
+	} else {
+		load := w.load(newPageNum)
+		select {
+		case <-load.done:
+			w.switchToPage(newPageNum, load.page)
+		default:
+			w.switchToPage(newPageNum, w.params.Pages.Loading)
+			go w.showWhenLoaded(newPageNum, load)
+		}
+	}
+	w.prefetchAround(newPageNum)
+}
+
+func (w *widget) switchToPage(newPageNum int, newPage *Page) {
+	oldPageNum := w.currentPageNum
+	w.currentPageNum = newPageNum // Required for rendering
+	content, embed := w.render(newPage.Default)
+	var err error
+	if len(w.msgFiles) == 0 && len(newPage.Files) == 0 {
+		_, err = w.messager.Edit(w.ctx, w.msg, content, embed)
+	} else {
+		err = w.replaceMsg(content, embed, newPage.Files)
+	}
 	if err != nil {
 		w.logger.Error(fmt.Errorf("Failed to show page: %w", err))
+		w.currentPageNum = oldPageNum
 		return
 	}
-	w.currentPageNum = newPageNum
 	w.currentPage = newPage
 	w.expanded = false
 	w.fixMoreLessReactsForCurrentPage()
 }
 
+// Replaces the widget message with a new one with the given files, since attachments cannot be
+// changed by editing.
+func (w *widget) replaceMsg(content string, embed *disgord.Embed, files []*File) error {
+	params := &disgord.CreateMessageParams{
+		Content: content,
+		Embed:   embed,
+		Files:   makeFileParams(files),
+	}
+	msg, err := w.messager.Send(w.ctx, w.channelID, params)
+	if err != nil {
+		return err
+	}
+	// The reacts go with the old message.
+	if err := w.messager.Delete(w.ctx, w.msg); err != nil {
+		w.logger.Error(fmt.Errorf("Delete replaced message failed: %w", err))
+	}
+	w.unpersist()
+	w.ctrl.kill()
+
+	w.msg = msg
+	w.msgFiles = files
+	w.currentReacts = make(map[string]bool)
+	w.addMissingControlReacts()
+	w.addListeners()
+	return nil
+}
+
+func makeFileParams(files []*File) []disgord.CreateMessageFileParams {
+	var params []disgord.CreateMessageFileParams
+	for _, file := range files {
+		params = append(params, disgord.CreateMessageFileParams{
+			Reader:   bytes.NewReader(file.Data),
+			FileName: file.Name,
+		})
+	}
+	return params
+}
+
+// Returns the page with the given number if it is got with Pages.Get, otherwise nil.
+func (w *widget) getPage(pageNum int) *Page {
+	if w.params.Pages.Get == nil {
+		return nil
+	}
+	return w.params.Pages.Get(pageNum)
+}
+
+// Returns the page with the given number, waiting for it to load if required. Only used before the
+// widget is listening.
+func (w *widget) waitForPage(pageNum int) (*Page, error) {
+	if page := w.getPage(pageNum); page != nil {
+		return page, nil
+	}
+	load := w.load(pageNum)
+	select {
+	case <-load.done:
+		return load.page, nil
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	}
+}
+
+// Returns the load of the given page's expansion, starting it if it was not started before or if
+// it failed.
+func (w *widget) loadExpansion(pageNum int, page *Page) *expansionLoad {
+	if load, ok := w.expansions[page]; ok {
+		select {
+		case <-load.done:
+			if load.err == nil {
+				return load
+			}
+		default:
+			return load
+		}
+	}
+	load := &expansionLoad{done: make(chan struct{})}
+	w.expansions[page] = load
+	go func() {
+		defer close(load.done)
+		load.msg, load.err = page.LoadExpanded(w.ctx)
+		if load.err != nil {
+			load.msg = w.params.Pages.ErrorPage(pageNum, load.err).Default
+		}
+	}()
+	return load
+}
+
+// Waits for the load and shows the loaded expansion, if the widget is still showing the page
+// expanded.
+func (w *widget) showExpansionWhenLoaded(page *Page, load *expansionLoad) {
+	select {
+	case <-load.done:
+	case <-w.ctx.Done():
+		return
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	if w.ctx.Err() != nil || w.currentPage != page || !w.expanded {
+		return
+	}
+	content, embed := w.render(load.msg)
+	if _, err := w.messager.Edit(w.ctx, w.msg, content, embed); err != nil {
+		w.logger.Error(fmt.Errorf("Failed to expand page: %w", err))
+	}
+}
+
+// Returns the load of the given page, starting it if it was not started before or if it failed.
+func (w *widget) load(pageNum int) *pageLoad {
+	if load, ok := w.loads[pageNum]; ok {
+		select {
+		case <-load.done:
+			if load.err == nil {
+				return load
+			}
+		default:
+			return load
+		}
+	}
+	load := &pageLoad{done: make(chan struct{})}
+	w.loads[pageNum] = load
+	go func() {
+		defer close(load.done)
+		load.page, load.err = w.params.Pages.Load(w.ctx, pageNum)
+		if load.err != nil {
+			load.page = w.params.Pages.ErrorPage(pageNum, load.err)
+		}
+	}()
+	return load
+}
+
+// Starts loading the pages next to the given page, if they are loaded.
+func (w *widget) prefetchAround(pageNum int) {
+	if w.params.Pages.Load == nil {
+		return
+	}
+	for _, adjPageNum := range []int{pageNum - 1, pageNum + 1} {
+		if adjPageNum >= 1 && adjPageNum <= w.params.Pages.Total && w.getPage(adjPageNum) == nil {
+			w.load(adjPageNum)
+		}
+	}
+}
+
+// Waits for the load and shows the loaded page, if the widget is still showing the loading page
+// for it.
+func (w *widget) showWhenLoaded(pageNum int, load *pageLoad) {
+	select {
+	case <-load.done:
+	case <-w.ctx.Done():
+		return
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	if w.ctx.Err() != nil || w.currentPageNum != pageNum || w.currentPage != w.params.Pages.Loading {
+		return
+	}
+	w.switchToPage(pageNum, load.page)
+	w.persist()
+}
+
+// Starts Refresh in the background, if set.
+func (w *widget) startRefresh() {
+	if w.params.Refresh != nil {
+		go w.params.Refresh(w.ctx, w.updatePage)
+	}
+}
+
+// Replaces the page with the given number, and shows it contracted if it is the current page.
+func (w *widget) updatePage(pageNum int, page *Page) {
+	w.Lock()
+	defer w.Unlock()
+	if w.ctx.Err() != nil || pageNum < 1 || pageNum > w.params.Pages.Total {
+		return
+	}
+	w.refreshed[pageNum] = page
+	if pageNum == w.currentPageNum {
+		w.switchToPage(pageNum, page)
+		w.persist()
+	}
+}
+
 func (w *widget) handleControlReact(evt *disgord.MessageReactionAdd) {
 	w.Lock()
 	defer w.Unlock()
@@ -325,6 +885,7 @@ func (w *widget) handleControlReact(evt *disgord.MessageReactionAdd) {
 	if react == delSymbol {
 		w.messager.Delete(w.ctx, w.msg)
 		w.params.DelCallback(evt)
+		w.unpersist()
 		w.cancel()
 		return
 	}
@@ -332,10 +893,14 @@ func (w *widget) handleControlReact(evt *disgord.MessageReactionAdd) {
 	go w.messager.UnreactUser(w.ctx, w.msg, react, evt.UserID)
 
 	switch evt.PartialEmoji.Name {
+	case firstSymbol:
+		w.showPage(1)
 	case prevSymbol:
-		w.showPage(-1)
+		w.showPage(w.currentPageNum - 1)
 	case nextSymbol:
-		w.showPage(+1)
+		w.showPage(w.currentPageNum + 1)
+	case lastSymbol:
+		w.showPage(w.params.Pages.Total)
 	case moreSymbol:
 		w.expandCurrentPage()
 	case lessSymbol:
@@ -343,4 +908,33 @@ func (w *widget) handleControlReact(evt *disgord.MessageReactionAdd) {
 	default:
 		w.logger.Error(fmt.Errorf("Unexpected react %v", react))
 	}
+	w.extendLife()
+	w.persist()
+}
+
+// Handles a reply to the widget message. If the reply is a page number, jumps to that page and
+// deletes the reply.
+func (w *widget) handleReply(reply *disgord.Message) {
+	pageNum, err := strconv.Atoi(strings.TrimSpace(reply.Content))
+	if err != nil || pageNum < 1 || pageNum > w.params.Pages.Total || reply.Author == nil {
+		return
+	}
+	evt := &disgord.MessageReactionAdd{
+		UserID:    reply.Author.ID,
+		ChannelID: reply.ChannelID,
+		MessageID: reply.MessageReference.MessageID,
+	}
+	if !w.params.AllowOp(OpNavigate, evt) {
+		return
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	// This will fail without manage messages permission, that's fine.
+	go w.messager.Delete(w.ctx, reply)
+
+	w.showPage(pageNum)
+	w.extendLife()
+	w.persist()
 }
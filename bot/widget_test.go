@@ -1,12 +1,18 @@
 package bot
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/andersfylling/disgord"
+	"github.com/go-test/deep"
 	"github.com/golang/mock/gomock"
 
 	"github.com/meooow25/cfspy/bot/mock_bot"
@@ -106,7 +112,7 @@ func newDelCallback(t *testing.T, expectedCalls int) DelCallbackType {
 
 func newAllowOp(t *testing.T, expectedCalls int) AllowPredicateType {
 	calls := 0
-	cb := func(evt *disgord.MessageReactionAdd) bool {
+	cb := func(WidgetOp, *disgord.MessageReactionAdd) bool {
 		calls++
 		return true
 	}
@@ -133,6 +139,14 @@ var _ gomock.Matcher = activeContextMatcher{}
 // Exposes convenient functions to record calls to a mock messager.
 type messagerCalls struct {
 	messager *mock_bot.MockMessager
+	msg      *disgord.Message // The widget message, testMsg if nil
+}
+
+func (c *messagerCalls) widgetMsg() *disgord.Message {
+	if c.msg == nil {
+		return testMsg
+	}
+	return c.msg
 }
 
 func (c *messagerCalls) send(
@@ -147,29 +161,29 @@ func (c *messagerCalls) send(
 	}
 	return c.messager.EXPECT().
 		Send(activeContextMatcher{}, testChannelID, params).
-		Return(testMsg, nil)
+		Return(c.widgetMsg(), nil)
 }
 
 func (c *messagerCalls) edit(content string, embed *disgord.Embed) *gomock.Call {
 	return c.messager.EXPECT().
-		Edit(activeContextMatcher{}, testMsg, content, embed).
-		Return(testMsg, nil)
+		Edit(activeContextMatcher{}, c.widgetMsg(), content, embed).
+		Return(c.widgetMsg(), nil)
 }
 
 func (c *messagerCalls) react(reaction string) *gomock.Call {
-	return c.messager.EXPECT().React(activeContextMatcher{}, testMsg, reaction)
+	return c.messager.EXPECT().React(activeContextMatcher{}, c.widgetMsg(), reaction)
 }
 
 func (c *messagerCalls) unreact(reaction string) *gomock.Call {
-	return c.messager.EXPECT().Unreact(activeContextMatcher{}, testMsg, reaction)
+	return c.messager.EXPECT().Unreact(activeContextMatcher{}, c.widgetMsg(), reaction)
 }
 
 func (c *messagerCalls) unreactUser(reaction string, userID disgord.Snowflake) *gomock.Call {
-	return c.messager.EXPECT().UnreactUser(activeContextMatcher{}, testMsg, reaction, userID)
+	return c.messager.EXPECT().UnreactUser(activeContextMatcher{}, c.widgetMsg(), reaction, userID)
 }
 
 func (c *messagerCalls) delete() *gomock.Call {
-	return c.messager.EXPECT().Delete(activeContextMatcher{}, testMsg)
+	return c.messager.EXPECT().Delete(activeContextMatcher{}, c.widgetMsg())
 }
 
 func (c *messagerCalls) reactListener(ch chan<- disgord.HandlerMessageReactionAdd) *gomock.Call {
@@ -182,10 +196,24 @@ func (c *messagerCalls) reactListener(ch chan<- disgord.HandlerMessageReactionAd
 		})
 }
 
+func (c *messagerCalls) replyListener(ch chan<- disgord.HandlerMessageCreate) *gomock.Call {
+	return c.messager.EXPECT().
+		AddReplyListener(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(_ disgord.Middleware, _ disgord.HandlerCtrl, handler disgord.HandlerMessageCreate) {
+			if ch != nil {
+				ch <- handler
+			}
+		})
+}
+
+func (c *messagerCalls) deleteReply(reply *disgord.Message) *gomock.Call {
+	return c.messager.EXPECT().Delete(activeContextMatcher{}, reply)
+}
+
 func TestWidgetOnePage(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	messager := mock_bot.NewMockMessager(ctrl)
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	gomock.InOrder(
 		calls.send(testPages[1].Default.Content, testPages[1].Default.Embed),
 		calls.react(delSymbol),
@@ -205,7 +233,7 @@ func TestWidgetOnePage(t *testing.T) {
 func TestWidgetMultiPage(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	messager := mock_bot.NewMockMessager(ctrl)
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	inOrder(
 		calls.send(testPages[2].Default.Content, testPages[2].Default.Embed),
 		calls.react(delSymbol),
@@ -241,7 +269,7 @@ func TestWidgetWithFiles(t *testing.T) {
 			Reader:   strings.NewReader("content2"),
 		},
 	}
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	inOrder(
 		calls.send(testPages[2].Default.Content, testPages[2].Default.Embed, files...),
 		calls.react(delSymbol),
@@ -267,7 +295,7 @@ func TestWidgetWithFiles(t *testing.T) {
 func TestWidgetCancel(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	messager := mock_bot.NewMockMessager(ctrl)
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	chk, _, _, _, _ := newCheckpoints()
 	inOrder(
 		calls.send(testPages[2].Default.Content, testPages[2].Default.Embed),
@@ -300,7 +328,7 @@ func TestWidgetCancel(t *testing.T) {
 func TestWidgetDelete(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	messager := mock_bot.NewMockMessager(ctrl)
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
 	gomock.InOrder(
 		calls.send(testPages[2].Default.Content, testPages[2].Default.Embed),
@@ -334,7 +362,7 @@ func TestWidgetDelete(t *testing.T) {
 func TestWidgetAllowOp(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	messager := mock_bot.NewMockMessager(ctrl)
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
 	chk, _, _, _, _ := newCheckpoints()
 
@@ -358,9 +386,9 @@ func TestWidgetAllowOp(t *testing.T) {
 
 	msgCallback := newMsgCallback(t, 1)
 	delCallback := newDelCallback(t, 1)
-	allow := false
-	allowOp := func(*disgord.MessageReactionAdd) bool {
-		return allow
+	allow := map[WidgetOp]bool{}
+	allowOp := func(op WidgetOp, _ *disgord.MessageReactionAdd) bool {
+		return allow[op]
 	}
 
 	w := newWidget(2, time.Minute, msgCallback, delCallback, allowOp, messager)
@@ -374,18 +402,20 @@ func TestWidgetAllowOp(t *testing.T) {
 	handler(nil, msgReactionAdd(delSymbol))
 
 	// Previous, 2 -> 1
-	allow = true
+	allow[OpNavigate] = true
 	handler(nil, msgReactionAdd(prevSymbol))
 	<-chk
 
-	// Not allowed
-	allow = false
+	// Delete not allowed
+	handler(nil, msgReactionAdd(delSymbol))
+
+	// Navigate not allowed
+	allow[OpNavigate] = false
+	allow[OpDelete] = true
 	handler(nil, msgReactionAdd(prevSymbol))
 	handler(nil, msgReactionAdd(nextSymbol))
-	handler(nil, msgReactionAdd(delSymbol))
 
 	// Delete
-	allow = true
 	handler(nil, msgReactionAdd(delSymbol))
 
 	if err := <-done; err != nil {
@@ -396,7 +426,7 @@ func TestWidgetAllowOp(t *testing.T) {
 func TestWidgetChangePage(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	messager := mock_bot.NewMockMessager(ctrl)
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
 	chk1, chk2, chk3, chk4, _ := newCheckpoints()
 
@@ -462,17 +492,20 @@ func TestWidgetChangePage(t *testing.T) {
 func TestWidgetExpandContract(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	messager := mock_bot.NewMockMessager(ctrl)
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
 	chk1, chk2, chk3, chk4, _ := newCheckpoints()
 
 	inOrder(
 		calls.send(testPages[3].Default.Content, testPages[3].Default.Embed),
 		calls.react(delSymbol),
+		calls.react(firstSymbol),
 		calls.react(prevSymbol),
 		calls.react(nextSymbol),
+		calls.react(lastSymbol),
 		calls.react(moreSymbol),
 		calls.reactListener(handlerCh),
+		calls.replyListener(nil),
 
 		// Expand, default -> expanded
 		anyOrder(
@@ -537,17 +570,20 @@ func TestWidgetExpandContract(t *testing.T) {
 func TestWidgetMixedActions(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	messager := mock_bot.NewMockMessager(ctrl)
-	calls := messagerCalls{messager}
+	calls := messagerCalls{messager: messager}
 	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
 	chk1, chk2, chk3, chk4, chk5 := newCheckpoints()
 
 	inOrder(
 		calls.send(testPages[4].Default.Content, testPages[4].Default.Embed),
 		calls.react(delSymbol),
+		calls.react(firstSymbol),
 		calls.react(prevSymbol),
 		calls.react(nextSymbol),
+		calls.react(lastSymbol),
 		calls.react(moreSymbol),
 		calls.reactListener(handlerCh),
+		calls.replyListener(nil),
 
 		// Expand 4, default -> expanded
 		anyOrder(
@@ -637,3 +673,917 @@ func TestWidgetMixedActions(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// A WidgetStore that keeps copies of states in memory.
+type testStore struct {
+	sync.Mutex
+	states map[disgord.Snowflake]WidgetState
+	saved  []WidgetState
+}
+
+func newTestStore(states ...*WidgetState) *testStore {
+	s := &testStore{states: make(map[disgord.Snowflake]WidgetState)}
+	for _, state := range states {
+		s.states[state.MessageID] = *state
+	}
+	return s
+}
+
+func (s *testStore) Save(state *WidgetState) error {
+	s.Lock()
+	defer s.Unlock()
+	stateCopy := *state
+	stateCopy.Reacts = append([]string(nil), state.Reacts...)
+	s.states[state.MessageID] = stateCopy
+	s.saved = append(s.saved, stateCopy)
+	return nil
+}
+
+func (s *testStore) Remove(messageID disgord.Snowflake) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.states, messageID)
+	return nil
+}
+
+func (s *testStore) LoadAll() ([]*WidgetState, error) {
+	s.Lock()
+	defer s.Unlock()
+	var states []*WidgetState
+	for _, state := range s.states {
+		stateCopy := state
+		states = append(states, &stateCopy)
+	}
+	return states, nil
+}
+
+var _ WidgetStore = (*testStore)(nil)
+
+func TestWidgetPersist(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk, _, _, _, _ := newCheckpoints()
+	inOrder(
+		calls.send(testPages[2].Default.Content, testPages[2].Default.Embed),
+		calls.react(delSymbol),
+		calls.react(prevSymbol),
+		calls.react(nextSymbol),
+		calls.reactListener(handlerCh),
+
+		// Previous, 2 -> 1
+		anyOrder(
+			calls.unreactUser(prevSymbol, testUserID),
+			calls.edit(testPages[1].Default.Content, testPages[1].Default.Embed),
+		),
+		chk,
+
+		calls.delete(),
+	)
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 1)
+	allowOp := newAllowOp(t, 2)
+
+	w := newWidget(2, time.Minute, msgCallback, delCallback, allowOp, messager)
+	store := newTestStore()
+	w.store = store
+	w.state = &WidgetState{Kind: "kind", Source: "source", TriggerAuthorID: testUserID}
+	done := runWidget(context.Background(), w)
+
+	handler := <-handlerCh
+	handler(nil, msgReactionAdd(prevSymbol))
+	<-chk
+	handler(nil, msgReactionAdd(delSymbol))
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.saved) != 2 {
+		t.Fatalf("got %v saves, want 2", len(store.saved))
+	}
+	for i, wantPageNum := range []int{2, 1} {
+		got := store.saved[i]
+		want := WidgetState{
+			ChannelID:       testChannelID,
+			MessageID:       testMsg.ID,
+			Kind:            "kind",
+			Source:          "source",
+			TriggerAuthorID: testUserID,
+			PageNum:         wantPageNum,
+			Reacts:          []string{prevSymbol, nextSymbol, delSymbol},
+			Expiry:          w.expiry,
+			MaxExpiry:       w.maxExpiry,
+		}
+		sort.Strings(want.Reacts)
+		if diff := deep.Equal(got, want); diff != nil {
+			t.Fatal(diff)
+		}
+	}
+	if len(store.states) != 0 {
+		t.Fatalf("got %v states after delete, want 0", len(store.states))
+	}
+}
+
+func TestWidgetPersistExpire(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	gomock.InOrder(
+		calls.send(testPages[1].Default.Content, testPages[1].Default.Embed),
+		calls.react(delSymbol),
+		calls.reactListener(nil),
+		calls.unreact(delSymbol),
+	)
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 0)
+
+	w := newWidget(1, time.Millisecond, msgCallback, delCallback, allowOp, messager)
+	store := newTestStore()
+	w.store = store
+	w.state = &WidgetState{}
+	if err := w.run(context.Background(), testChannelID); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.saved) != 1 {
+		t.Fatalf("got %v saves, want 1", len(store.saved))
+	}
+	if len(store.states) != 0 {
+		t.Fatalf("got %v states after expiry, want 0", len(store.states))
+	}
+}
+
+func TestWidgetResume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk, _, _, _, _ := newCheckpoints()
+	inOrder(
+		// Refresh, page 3 was expanded before restart
+		calls.edit(testPages[3].Expanded.Content, testPages[3].Expanded.Embed),
+		calls.react(firstSymbol),
+		calls.react(lastSymbol),
+		calls.reactListener(handlerCh),
+		calls.replyListener(nil),
+
+		// Next, 3 -> 4
+		anyOrder(
+			calls.unreactUser(nextSymbol, testUserID),
+			inOrder(
+				calls.edit(testPages[4].Default.Content, testPages[4].Default.Embed),
+				calls.unreact(lessSymbol),
+				calls.react(moreSymbol),
+			),
+		),
+		chk,
+
+		calls.delete(),
+	)
+	msgCallback := newMsgCallback(t, 0)
+	delCallback := newDelCallback(t, 1)
+	allowOp := newAllowOp(t, 2)
+
+	w := newWidget(4, time.Minute, msgCallback, delCallback, allowOp, messager)
+	state := &WidgetState{
+		MessageID: testMsg.ID,
+		PageNum:   3,
+		Expanded:  true,
+		Reacts:    []string{delSymbol, lessSymbol, nextSymbol, prevSymbol},
+		Expiry:    time.Now().Add(time.Minute),
+	}
+	store := newTestStore(state)
+	w.store = store
+	done := make(chan error)
+	go func() { done <- w.resume(context.Background(), state) }()
+
+	handler := <-handlerCh
+	handler(nil, msgReactionAdd(nextSymbol))
+	<-chk
+	handler(nil, msgReactionAdd(delSymbol))
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	var gotPageNums []int
+	for _, saved := range store.saved {
+		gotPageNums = append(gotPageNums, saved.PageNum)
+	}
+	if diff := deep.Equal(gotPageNums, []int{3, 4}); diff != nil {
+		t.Fatal(diff)
+	}
+	if len(store.states) != 0 {
+		t.Fatalf("got %v states after delete, want 0", len(store.states))
+	}
+}
+
+func TestCleanupPersistedWidget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	msg := &disgord.Message{ID: testMsg.ID, ChannelID: testChannelID}
+	messager.EXPECT().Unreact(gomock.Any(), msg, delSymbol)
+	messager.EXPECT().Unreact(gomock.Any(), msg, moreSymbol)
+
+	state := &WidgetState{
+		MessageID: testMsg.ID,
+		ChannelID: testChannelID,
+		Reacts:    []string{delSymbol, moreSymbol},
+	}
+	store := newTestStore(state)
+	cleanupPersistedWidget(context.Background(), state, store, messager, nil)
+	if len(store.states) != 0 {
+		t.Fatalf("got %v states after cleanup, want 0", len(store.states))
+	}
+}
+
+func testReply(content string) *disgord.Message {
+	return &disgord.Message{
+		Content:          content,
+		Author:           &disgord.User{ID: testUserID},
+		MessageReference: &disgord.MessageReference{MessageID: testMsg.ID},
+	}
+}
+
+func TestWidgetFirstLastJump(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	replyHandlerCh := make(chan disgord.HandlerMessageCreate, 1)
+	chk1, chk2, chk3, chk4, _ := newCheckpoints()
+	reply2, reply2Again := testReply("2"), testReply(" 2 ")
+
+	inOrder(
+		calls.send(testPages[4].Default.Content, testPages[4].Default.Embed),
+		calls.react(delSymbol),
+		calls.react(firstSymbol),
+		calls.react(prevSymbol),
+		calls.react(nextSymbol),
+		calls.react(lastSymbol),
+		calls.react(moreSymbol),
+		calls.reactListener(handlerCh),
+		calls.replyListener(replyHandlerCh),
+
+		// First, 4 -> 1
+		anyOrder(
+			calls.unreactUser(firstSymbol, testUserID),
+			inOrder(
+				calls.edit(testPages[1].Default.Content, testPages[1].Default.Embed),
+				calls.unreact(moreSymbol),
+			),
+		),
+		chk1,
+
+		// Last, 1 -> 4
+		anyOrder(
+			calls.unreactUser(lastSymbol, testUserID),
+			inOrder(
+				calls.edit(testPages[4].Default.Content, testPages[4].Default.Embed),
+				calls.react(moreSymbol),
+			),
+		),
+		chk2,
+
+		// Jump, 4 -> 2
+		anyOrder(
+			calls.deleteReply(reply2),
+			inOrder(
+				calls.edit(testPages[2].Default.Content, testPages[2].Default.Embed),
+				calls.unreact(moreSymbol),
+			),
+		),
+		chk3,
+
+		// Jump, 2 -> 2
+		calls.deleteReply(reply2Again),
+		chk4,
+	)
+
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 4)
+
+	w := newWidget(4, time.Minute, msgCallback, delCallback, allowOp, messager)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runWidget(ctx, w)
+
+	handler := <-handlerCh
+	replyHandler := <-replyHandlerCh
+
+	// First, 4 -> 1
+	handler(nil, msgReactionAdd(firstSymbol))
+	<-chk1
+
+	// Last, 1 -> 4
+	handler(nil, msgReactionAdd(lastSymbol))
+	<-chk2
+
+	// Not page numbers, ignored
+	replyHandler(nil, &disgord.MessageCreate{Message: testReply("hello")})
+	replyHandler(nil, &disgord.MessageCreate{Message: testReply("0")})
+	replyHandler(nil, &disgord.MessageCreate{Message: testReply("5")})
+
+	// Jump, 4 -> 2
+	replyHandler(nil, &disgord.MessageCreate{Message: reply2})
+	<-chk3
+
+	// Jump, 2 -> 2
+	replyHandler(nil, &disgord.MessageCreate{Message: reply2Again})
+	<-chk4
+}
+
+func TestWidgetIndicator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk, _, _, _, _ := newCheckpoints()
+	withFooter := func(embed *disgord.Embed, footer string) *disgord.Embed {
+		embedCopy := *embed
+		embedCopy.Footer = &disgord.EmbedFooter{Text: footer}
+		return &embedCopy
+	}
+
+	inOrder(
+		calls.send(testPages[2].Default.Content, withFooter(testPages[2].Default.Embed, "2 of 2")),
+		calls.react(delSymbol),
+		calls.react(prevSymbol),
+		calls.react(nextSymbol),
+		calls.reactListener(handlerCh),
+
+		// Previous, 2 -> 1
+		anyOrder(
+			calls.unreactUser(prevSymbol, testUserID),
+			calls.edit(testPages[1].Default.Content, withFooter(testPages[1].Default.Embed, "1 of 2")),
+		),
+		chk,
+	)
+
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 1)
+
+	w := newWidget(2, time.Minute, msgCallback, delCallback, allowOp, messager)
+	w.params.Pages.Indicator = func(pageNum, total int) string {
+		return fmt.Sprintf("%v of %v", pageNum, total)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runWidget(ctx, w)
+
+	handler := <-handlerCh
+	handler(nil, msgReactionAdd(prevSymbol))
+	<-chk
+
+	// The pages themselves are not modified
+	if testPages[1].Default.Embed.Footer != nil || testPages[2].Default.Embed.Footer != nil {
+		t.Fatal("page embeds modified")
+	}
+}
+
+func TestWidgetExtendLife(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk, _, _, _, _ := newCheckpoints()
+
+	inOrder(
+		calls.send(testPages[2].Default.Content, testPages[2].Default.Embed),
+		calls.react(delSymbol),
+		calls.react(prevSymbol),
+		calls.react(nextSymbol),
+		calls.reactListener(handlerCh),
+
+		// Previous, 2 -> 1
+		anyOrder(
+			calls.unreactUser(prevSymbol, testUserID),
+			calls.edit(testPages[1].Default.Content, testPages[1].Default.Embed),
+		),
+		chk,
+	)
+
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 1)
+
+	w := newWidget(2, time.Minute, msgCallback, delCallback, allowOp, messager)
+	w.params.MaxLifetime = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runWidget(ctx, w)
+
+	handler := <-handlerCh
+	w.Lock()
+	initialExpiry := w.expiry
+	w.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	handler(nil, msgReactionAdd(prevSymbol))
+	<-chk
+
+	w.Lock()
+	defer w.Unlock()
+	if !w.expiry.After(initialExpiry) {
+		t.Fatalf("got expiry %v, want after %v", w.expiry, initialExpiry)
+	}
+	if w.expiry.After(w.maxExpiry) {
+		t.Fatalf("got expiry %v, want not after %v", w.expiry, w.maxExpiry)
+	}
+}
+
+func TestWidgetPageFiles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	msg2, msg3 := &disgord.Message{ID: 238649}, &disgord.Message{ID: 238650}
+	calls := messagerCalls{messager: messager}
+	calls2 := messagerCalls{messager: messager, msg: msg2}
+	calls3 := messagerCalls{messager: messager, msg: msg3}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk1, chk2, _, _, _ := newCheckpoints()
+	pageWithFiles := NewPage("test files", &disgord.Embed{Title: "embed files"})
+	pageWithFiles.Files = []*File{{Name: "file.txt", Data: []byte("content")}}
+	fileParams := disgord.CreateMessageFileParams{
+		FileName: "file.txt",
+		Reader:   bytes.NewReader([]byte("content")),
+	}
+
+	inOrder(
+		calls.send(testPages[1].Default.Content, testPages[1].Default.Embed),
+		calls.react(delSymbol),
+		calls.react(prevSymbol),
+		calls.react(nextSymbol),
+		calls.reactListener(handlerCh),
+
+		// Next, 1 -> 2, replaced by a message with files
+		anyOrder(
+			calls.unreactUser(nextSymbol, testUserID),
+			inOrder(
+				calls2.send(pageWithFiles.Default.Content, pageWithFiles.Default.Embed, fileParams),
+				calls.delete(),
+				calls2.react(delSymbol),
+				calls2.react(prevSymbol),
+				calls2.react(nextSymbol),
+				calls2.reactListener(handlerCh),
+			),
+		),
+		chk1,
+
+		// Previous, 2 -> 1, replaced by a message without files
+		anyOrder(
+			calls2.unreactUser(prevSymbol, testUserID),
+			inOrder(
+				calls3.send(testPages[1].Default.Content, testPages[1].Default.Embed),
+				calls2.delete(),
+				calls3.react(delSymbol),
+				calls3.react(prevSymbol),
+				calls3.react(nextSymbol),
+				calls3.reactListener(handlerCh),
+			),
+		),
+		chk2,
+	)
+
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 2)
+
+	w := newWidget(2, time.Minute, msgCallback, delCallback, allowOp, messager)
+	w.params.Pages.Get = func(i int) *Page {
+		if i == 2 {
+			return pageWithFiles
+		}
+		return testPages[i]
+	}
+	w.params.Pages.First = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runWidget(ctx, w)
+
+	// Next, 1 -> 2
+	handler := <-handlerCh
+	handler(nil, msgReactionAdd(nextSymbol))
+	<-chk1
+
+	// Previous, 2 -> 1
+	handler = <-handlerCh
+	handler(nil, msgReactionAdd(prevSymbol))
+	<-chk2
+}
+
+func TestWidgetLoad(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk1, chk2, chk3, chk4, chk5 := newCheckpoints()
+	loading := defaultLoadingPage.Default
+	errorEmbed := &disgord.Embed{Description: "page 1 failed"}
+
+	inOrder(
+		calls.send(testPages[4].Default.Content, testPages[4].Default.Embed),
+		calls.react(delSymbol),
+		calls.react(firstSymbol),
+		calls.react(prevSymbol),
+		calls.react(nextSymbol),
+		calls.react(lastSymbol),
+		calls.react(moreSymbol),
+		calls.reactListener(handlerCh),
+		calls.replyListener(nil),
+
+		// Previous, 4 -> 3, loading
+		anyOrder(
+			calls.unreactUser(prevSymbol, testUserID),
+			inOrder(
+				calls.edit(loading.Content, loading.Embed),
+				calls.unreact(moreSymbol),
+			),
+		),
+		chk1,
+
+		// Page 3 loaded
+		calls.edit(testPages[3].Default.Content, testPages[3].Default.Embed),
+		calls.react(moreSymbol),
+		chk2,
+
+		// Next, 3 -> 4, already loaded
+		anyOrder(
+			calls.unreactUser(nextSymbol, testUserID),
+			calls.edit(testPages[4].Default.Content, testPages[4].Default.Embed),
+		),
+		chk3,
+
+		// First, 4 -> 1, loading
+		anyOrder(
+			calls.unreactUser(firstSymbol, testUserID),
+			inOrder(
+				calls.edit(loading.Content, loading.Embed),
+				calls.unreact(moreSymbol),
+			),
+		),
+		chk4,
+
+		// Page 1 failed
+		calls.edit("", errorEmbed),
+		chk5,
+	)
+
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 3)
+
+	release := make(map[int]chan struct{})
+	for i := 1; i <= 4; i++ {
+		release[i] = make(chan struct{})
+	}
+	close(release[4])
+	var loadsMu sync.Mutex
+	loads := make(map[int]int)
+
+	w := newWidget(4, time.Minute, msgCallback, delCallback, allowOp, messager)
+	w.params.Pages.Get = nil
+	w.params.Pages.Load = func(ctx context.Context, i int) (*Page, error) {
+		loadsMu.Lock()
+		loads[i]++
+		loadsMu.Unlock()
+		select {
+		case <-release[i]:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if i == 1 {
+			return nil, errors.New("page 1 failed")
+		}
+		return testPages[i], nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runWidget(ctx, w)
+	handler := <-handlerCh
+
+	// Previous, 4 -> 3
+	handler(nil, msgReactionAdd(prevSymbol))
+	<-chk1
+	close(release[3])
+	<-chk2
+
+	// Next, 3 -> 4
+	handler(nil, msgReactionAdd(nextSymbol))
+	<-chk3
+
+	// First, 4 -> 1
+	handler(nil, msgReactionAdd(firstSymbol))
+	<-chk4
+	close(release[1])
+	<-chk5
+
+	cancel()
+	<-done
+
+	// Each page is loaded once, page 2 is prefetched
+	if diff := deep.Equal(loads, map[int]int{1: 1, 2: 1, 3: 1, 4: 1}); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestWidgetGetAndLoad(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk1, chk2, chk3, _, _ := newCheckpoints()
+	loading := defaultLoadingPage.Default
+
+	inOrder(
+		calls.send(testPages[4].Default.Content, testPages[4].Default.Embed),
+		calls.react(delSymbol),
+		calls.react(firstSymbol),
+		calls.react(prevSymbol),
+		calls.react(nextSymbol),
+		calls.react(lastSymbol),
+		calls.react(moreSymbol),
+		calls.reactListener(handlerCh),
+		calls.replyListener(nil),
+
+		// Previous, 4 -> 3, got
+		anyOrder(
+			calls.unreactUser(prevSymbol, testUserID),
+			calls.edit(testPages[3].Default.Content, testPages[3].Default.Embed),
+		),
+		chk1,
+
+		// Previous, 3 -> 2, loading
+		anyOrder(
+			calls.unreactUser(prevSymbol, testUserID),
+			inOrder(
+				calls.edit(loading.Content, loading.Embed),
+				calls.unreact(moreSymbol),
+			),
+		),
+		chk2,
+
+		// Page 2 loaded
+		calls.edit(testPages[2].Default.Content, testPages[2].Default.Embed),
+		chk3,
+	)
+
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 2)
+
+	release := make(chan struct{})
+	w := newWidget(4, time.Minute, msgCallback, delCallback, allowOp, messager)
+	w.params.Pages.Get = func(i int) *Page {
+		if i < 3 {
+			return nil
+		}
+		return testPages[i]
+	}
+	w.params.Pages.Load = func(ctx context.Context, i int) (*Page, error) {
+		if i >= 3 {
+			t.Errorf("page %v loaded, want got", i)
+		}
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return testPages[i], nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runWidget(ctx, w)
+	handler := <-handlerCh
+
+	// Previous, 4 -> 3
+	handler(nil, msgReactionAdd(prevSymbol))
+	<-chk1
+
+	// Previous, 3 -> 2
+	handler(nil, msgReactionAdd(prevSymbol))
+	<-chk2
+	close(release)
+	<-chk3
+
+	cancel()
+	<-done
+}
+
+func TestWidgetLoadExpanded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk1, chk2, chk3, chk4, chk5 := newCheckpoints()
+	chk6, chk7, _, _, _ := newCheckpoints()
+	loading := defaultLoadingPage.Default
+	errorEmbed := &disgord.Embed{Description: "expansion failed"}
+	page := NewPage("test", &disgord.Embed{Title: "embed"})
+	expanded := &Message{Content: "test expanded", Embed: &disgord.Embed{Title: "embed expanded"}}
+
+	expandLoading := func() *group {
+		return anyOrder(
+			calls.unreactUser(moreSymbol, testUserID),
+			inOrder(
+				calls.edit(loading.Content, loading.Embed),
+				calls.unreact(moreSymbol),
+				calls.react(lessSymbol),
+			),
+		)
+	}
+	contract := func() *group {
+		return anyOrder(
+			calls.unreactUser(lessSymbol, testUserID),
+			inOrder(
+				calls.edit(page.Default.Content, page.Default.Embed),
+				calls.unreact(lessSymbol),
+				calls.react(moreSymbol),
+			),
+		)
+	}
+
+	inOrder(
+		calls.send(page.Default.Content, page.Default.Embed),
+		calls.react(delSymbol),
+		calls.react(moreSymbol),
+		calls.reactListener(handlerCh),
+
+		// Expand, loading
+		expandLoading(),
+		chk1,
+
+		// Expansion failed
+		calls.edit("", errorEmbed),
+		chk2,
+
+		// Contract
+		contract(),
+		chk3,
+
+		// Expand, loading again
+		expandLoading(),
+		chk4,
+
+		// Expansion loaded
+		calls.edit(expanded.Content, expanded.Embed),
+		chk5,
+
+		// Contract
+		contract(),
+		chk6,
+
+		// Expand, already loaded
+		anyOrder(
+			calls.unreactUser(moreSymbol, testUserID),
+			inOrder(
+				calls.edit(expanded.Content, expanded.Embed),
+				calls.unreact(moreSymbol),
+				calls.react(lessSymbol),
+			),
+		),
+		chk7,
+	)
+
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 5)
+
+	release := make(chan error)
+	loads := 0
+	page.LoadExpanded = func(ctx context.Context) (*Message, error) {
+		loads++
+		select {
+		case err := <-release:
+			if err != nil {
+				return nil, err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return expanded, nil
+	}
+
+	w := newWidget(1, time.Minute, msgCallback, delCallback, allowOp, messager)
+	w.params.Pages.Get = func(int) *Page { return page }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runWidget(ctx, w)
+	handler := <-handlerCh
+
+	// Expand, the first load fails
+	handler(nil, msgReactionAdd(moreSymbol))
+	<-chk1
+	release <- errors.New("expansion failed")
+	<-chk2
+
+	// Contract and expand, the expansion is loaded again
+	handler(nil, msgReactionAdd(lessSymbol))
+	<-chk3
+	handler(nil, msgReactionAdd(moreSymbol))
+	<-chk4
+	release <- nil
+	<-chk5
+
+	// Contract and expand, the loaded expansion is shown
+	handler(nil, msgReactionAdd(lessSymbol))
+	<-chk6
+	handler(nil, msgReactionAdd(moreSymbol))
+	<-chk7
+
+	cancel()
+	<-done
+
+	if loads != 2 {
+		t.Fatalf("got %v loads, want 2", loads)
+	}
+}
+
+func TestWidgetRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	handlerCh := make(chan disgord.HandlerMessageReactionAdd, 1)
+	chk1, chk2, _, _, _ := newCheckpoints()
+	refreshed1 := NewPage("refreshed1", &disgord.Embed{Title: "refreshed embed1"})
+	refreshed2 := NewPage("refreshed2", &disgord.Embed{Title: "refreshed embed2"})
+
+	inOrder(
+		calls.send(testPages[2].Default.Content, testPages[2].Default.Embed),
+		calls.react(delSymbol),
+		calls.react(prevSymbol),
+		calls.react(nextSymbol),
+		calls.reactListener(handlerCh),
+
+		// Page 2 refreshed while shown
+		calls.edit(refreshed2.Default.Content, refreshed2.Default.Embed),
+		chk1,
+
+		// Previous, 2 -> 1, refreshed while not shown
+		anyOrder(
+			calls.unreactUser(prevSymbol, testUserID),
+			calls.edit(refreshed1.Default.Content, refreshed1.Default.Embed),
+		),
+		chk2,
+	)
+
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 1)
+
+	w := newWidget(2, time.Minute, msgCallback, delCallback, allowOp, messager)
+	updateCh := make(chan func(int, *Page), 1)
+	refreshDone := make(chan struct{})
+	w.params.Refresh = func(ctx context.Context, update func(int, *Page)) {
+		updateCh <- update
+		<-ctx.Done()
+		close(refreshDone)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runWidget(ctx, w)
+	handler := <-handlerCh
+	update := <-updateCh
+
+	update(1, refreshed1)
+	update(2, refreshed2)
+	<-chk1
+
+	// Previous, 2 -> 1
+	handler(nil, msgReactionAdd(prevSymbol))
+	<-chk2
+
+	cancel()
+	<-done
+	<-refreshDone
+
+	// Ignored once the widget is done
+	update(1, testPages[1])
+}
+
+func TestWidgetFitsEmbed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	messager := mock_bot.NewMockMessager(ctrl)
+	calls := messagerCalls{messager: messager}
+	longPage := NewPage("test long", &disgord.Embed{Title: strings.Repeat("a", 300)})
+	gomock.InOrder(
+		calls.send("test long", &disgord.Embed{Title: strings.Repeat("a", 255) + "…"}),
+		calls.react(delSymbol),
+		calls.reactListener(nil),
+		calls.unreact(delSymbol),
+	)
+	msgCallback := newMsgCallback(t, 1)
+	delCallback := newDelCallback(t, 0)
+	allowOp := newAllowOp(t, 0)
+
+	w := newWidget(1, time.Millisecond, msgCallback, delCallback, allowOp, messager)
+	w.params.Pages.Get = func(int) *Page { return longPage }
+	if err := w.run(context.Background(), testChannelID); err != nil {
+		t.Fatal(err)
+	}
+}
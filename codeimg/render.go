@@ -0,0 +1,248 @@
+// Package codeimg renders syntax highlighted code with line numbers to PNG images, in pure Go with
+// the bundled Go Mono font.
+package codeimg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Kind is the kind of a span of code, which decides its color.
+type Kind int
+
+// Kinds of spans.
+const (
+	Plain Kind = iota // Identifiers, whitespace and anything else not highlighted
+	Keyword
+	String
+	Number
+	Comment
+	Punct
+)
+
+// Span is a span of code of one kind.
+type Span struct {
+	Text string
+	Kind Kind
+}
+
+// Line is a line of code. Number is shown in the gutter if positive.
+type Line struct {
+	Number   int
+	Selected bool // Selected lines have a highlighted background
+	Gap      bool // Marks skipped lines, drawn as … in the gutter instead of code
+	Spans    []Span
+}
+
+// Theme is the colors of an image.
+type Theme struct {
+	Background color.RGBA
+	Selected   color.RGBA // Background of selected lines
+	Gutter     color.RGBA // Line numbers and the separator
+	Colors     map[Kind]color.RGBA
+}
+
+// Themes are the bundled themes by name.
+var Themes = map[string]*Theme{
+	"dark": {
+		Background: color.RGBA{0x2b, 0x2d, 0x31, 0xff}, // Discord dark
+		Selected:   color.RGBA{0x3a, 0x40, 0x33, 0xff},
+		Gutter:     color.RGBA{0x80, 0x84, 0x8e, 0xff},
+		Colors: map[Kind]color.RGBA{
+			Plain:   {0xdc, 0xdd, 0xde, 0xff},
+			Keyword: {0xc6, 0x78, 0xdd, 0xff},
+			String:  {0x98, 0xc3, 0x79, 0xff},
+			Number:  {0xd1, 0x9a, 0x66, 0xff},
+			Comment: {0x7f, 0x84, 0x8e, 0xff},
+			Punct:   {0x61, 0xaf, 0xef, 0xff},
+		},
+	},
+	"light": {
+		Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Selected:   color.RGBA{0xe6, 0xf4, 0xd9, 0xff},
+		Gutter:     color.RGBA{0x99, 0x99, 0x99, 0xff},
+		Colors: map[Kind]color.RGBA{
+			Plain:   {0x38, 0x3a, 0x42, 0xff},
+			Keyword: {0xa6, 0x26, 0xa4, 0xff},
+			String:  {0x50, 0xa1, 0x4f, 0xff},
+			Number:  {0x98, 0x68, 0x01, 0xff},
+			Comment: {0xa0, 0xa1, 0xa7, 0xff},
+			Punct:   {0x40, 0x78, 0xf2, 0xff},
+		},
+	},
+}
+
+// Sizes in pixels.
+const (
+	fontSize   = 16 // Pixels per em
+	lineHeight = 22 // Baseline to baseline
+	ascent     = 16 // Top of a line to the baseline
+	tabWidth   = 4  // In cells
+	margin     = 12
+	maxColumns = 120 // Longer lines are cut off with …
+	maxLines   = 300
+)
+
+// Go Mono, bundled in golang.org/x/image.
+var monoFont = mustParseFont(gomono.TTF)
+
+// Width of a cell, the advance of every rune in the monospace font.
+var cellWidth = func() float64 {
+	advance, _ := newFace().GlyphAdvance('0')
+	return float64(advance) / 64
+}()
+
+func mustParseFont(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Returns a face of the font. Faces are not safe for concurrent use, so every image gets its own.
+func newFace() font.Face {
+	face, err := opentype.NewFace(monoFont, &opentype.FaceOptions{
+		Size: fontSize, DPI: 72, Hinting: font.HintingFull,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return face
+}
+
+var (
+	// ErrTooLarge is returned for code with too many lines to render.
+	ErrTooLarge = errors.New("Code too large to render")
+	// ErrMissingGlyph is returned for code with runes the font cannot draw, like CJK text.
+	ErrMissingGlyph = errors.New("No glyph in the font")
+)
+
+// RenderPNG renders the lines of code with the theme.
+func RenderPNG(lines []*Line, theme *Theme) ([]byte, error) {
+	if len(lines) == 0 || len(lines) > maxLines {
+		return nil, ErrTooLarge
+	}
+	var buf sfnt.Buffer
+	rows := make([][]cell, len(lines))
+	columns, maxNumber := 0, 0
+	for i, line := range lines {
+		rows[i] = layoutLine(line)
+		for _, cell := range rows[i] {
+			if index, err := monoFont.GlyphIndex(&buf, cell.r); err != nil || index == 0 {
+				return nil, fmt.Errorf("%w: %q", ErrMissingGlyph, cell.r)
+			}
+		}
+		if len(rows[i]) > columns {
+			columns = len(rows[i])
+		}
+		if line.Number > maxNumber {
+			maxNumber = line.Number
+		}
+	}
+	gutterColumns := 0
+	if maxNumber > 0 {
+		gutterColumns = len(strconv.Itoa(maxNumber))
+	}
+	codeStart := float64(gutterColumns) * cellWidth
+	if gutterColumns > 0 {
+		codeStart += 2 * cellWidth // Space on either side of the separator
+	}
+
+	width := int(math.Ceil(codeStart+float64(columns)*cellWidth)) + 2*margin
+	height := len(lines)*lineHeight + 2*margin
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill := func(r image.Rectangle, col color.RGBA) {
+		draw.Draw(img, r, &image.Uniform{col}, image.Point{}, draw.Src)
+	}
+	fill(img.Bounds(), theme.Background)
+	for i, line := range lines {
+		if line.Selected {
+			top := margin + i*lineHeight
+			fill(image.Rect(0, top, width, top+lineHeight), theme.Selected)
+		}
+	}
+	if gutterColumns > 0 {
+		x := margin + int((float64(gutterColumns)+1)*cellWidth)
+		fill(image.Rect(x, margin, x+1, height-margin), theme.Gutter)
+	}
+
+	face := newFace()
+	defer face.Close()
+	d := &font.Drawer{Dst: img, Face: face}
+	// Draws a rune in the cell at x, in pixels from the left end of the code.
+	drawRune := func(r rune, x float64, baseline int, col color.RGBA) {
+		d.Src = &image.Uniform{col}
+		d.Dot = fixed.Point26_6{X: fixed.Int26_6((margin + x) * 64), Y: fixed.I(baseline)}
+		d.DrawString(string(r))
+	}
+	for i, line := range lines {
+		baseline := margin + i*lineHeight + ascent
+		var gutter string
+		switch {
+		case line.Gap:
+			gutter = "…"
+		case line.Number > 0:
+			gutter = strconv.Itoa(line.Number)
+		}
+		gutterRunes := []rune(gutter) // Right aligned
+		for j, r := range gutterRunes {
+			col := gutterColumns - len(gutterRunes) + j
+			drawRune(r, float64(col)*cellWidth, baseline, theme.Gutter)
+		}
+		for col, cell := range rows[i] {
+			drawRune(cell.r, codeStart+float64(col)*cellWidth, baseline, theme.Colors[cell.kind])
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// A rune of code in a cell, with the kind of its span.
+type cell struct {
+	r    rune
+	kind Kind
+}
+
+// Returns the cells of a line, with tabs expanded and code past maxColumns cut off.
+func layoutLine(line *Line) []cell {
+	if line.Gap {
+		return nil
+	}
+	var cells []cell
+	for _, span := range line.Spans {
+		for _, r := range span.Text {
+			if r == '\t' {
+				for n := tabWidth - len(cells)%tabWidth; n > 0; n-- {
+					cells = append(cells, cell{' ', span.Kind})
+				}
+				continue
+			}
+			cells = append(cells, cell{r, span.Kind})
+		}
+	}
+	for len(cells) > 0 && cells[len(cells)-1].r == ' ' {
+		cells = cells[:len(cells)-1]
+	}
+	if len(cells) > maxColumns {
+		cells = append(cells[:maxColumns-1], cell{'…', Comment})
+	}
+	return cells
+}
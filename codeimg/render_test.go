@@ -0,0 +1,98 @@
+package codeimg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestRenderPNG(t *testing.T) {
+	theme := Themes["dark"]
+	lines := []*Line{
+		{Number: 9, Spans: []Span{{"int", Keyword}, {" x = ", Plain}, {"1", Number}}},
+		{Gap: true},
+		{Number: 12, Selected: true, Spans: []Span{{"\treturn", Keyword}, {" ж;", Plain}}},
+	}
+	data, err := RenderPNG(lines, theme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two gutter columns, the separator and 13 code columns, with the tab expanded
+	size := img.Bounds().Size()
+	if wantWidth := int(math.Ceil((2+2+13)*cellWidth)) + 2*margin; size.X != wantWidth {
+		t.Fatalf("got width %v, want %v", size.X, wantWidth)
+	}
+	if wantHeight := 3*lineHeight + 2*margin; size.Y != wantHeight {
+		t.Fatalf("got height %v, want %v", size.Y, wantHeight)
+	}
+
+	// Corners are background, the right end of the selected line is highlighted
+	if r, g, b, _ := img.At(0, 0).RGBA(); uint8(r>>8) != theme.Background.R ||
+		uint8(g>>8) != theme.Background.G || uint8(b>>8) != theme.Background.B {
+		t.Fatalf("got corner %v, want the background", img.At(0, 0))
+	}
+	y := margin + int(2.5*lineHeight)
+	if r, g, b, _ := img.At(size.X-1, y).RGBA(); uint8(r>>8) != theme.Selected.R ||
+		uint8(g>>8) != theme.Selected.G || uint8(b>>8) != theme.Selected.B {
+		t.Fatalf("got %v on the selected line, want the highlight", img.At(size.X-1, y))
+	}
+
+	// Cyrillic is drawn, ж is in the cell after "    return "
+	x, top := margin+int((2+2+11)*cellWidth), margin+2*lineHeight
+	cell := image.Rect(x, top, x+int(cellWidth), top+lineHeight)
+	if got := countColor(img, cell, theme.Colors[Plain]); got == 0 {
+		t.Fatalf("got no pixels of ж in the cell %v", cell)
+	}
+
+	lines = []*Line{{Number: 1, Spans: []Span{{"// 漢字", Comment}}}}
+	if _, err := RenderPNG(lines, theme); !errors.Is(err, ErrMissingGlyph) {
+		t.Fatalf("got error %v for CJK, want %v", err, ErrMissingGlyph)
+	}
+	if _, err := RenderPNG(nil, theme); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("got error %v for no lines, want %v", err, ErrTooLarge)
+	}
+	if _, err := RenderPNG(make([]*Line, maxLines+1), theme); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("got error %v for too many lines, want %v", err, ErrTooLarge)
+	}
+}
+
+// Returns the number of pixels of the color in the rectangle.
+func countColor(img image.Image, r image.Rectangle, col color.RGBA) int {
+	count := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if img.At(x, y) == col {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestLayoutLine(t *testing.T) {
+	cells := layoutLine(&Line{Spans: []Span{{"a\tb", Plain}, {"  ", Plain}}})
+	var got []rune
+	for _, c := range cells {
+		got = append(got, c.r)
+	}
+	if string(got) != "a   b" {
+		t.Fatalf("got %q, want tabs expanded and trailing space trimmed", string(got))
+	}
+
+	long := make([]byte, maxColumns+10)
+	for i := range long {
+		long[i] = 'x'
+	}
+	cells = layoutLine(&Line{Spans: []Span{{string(long), Plain}}})
+	if len(cells) != maxColumns || cells[len(cells)-1].r != '…' {
+		t.Fatalf("got %v cells, want %v ending in …", len(cells), maxColumns)
+	}
+}
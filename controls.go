@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/meooow25/cfspy/bot"
+)
+
+// Who may control a widget, in addition to the author of the message that triggered it.
+const (
+	whoAuthor   = "author"
+	whoAnyone   = "anyone"
+	whoManagers = "managers"
+	whoRoles    = "roles"
+)
+
+// controlPolicy is who may perform an operation on a widget. The author of the message that
+// triggered the widget is always allowed.
+type controlPolicy struct {
+	Who   string              `json:"who"`
+	Roles []disgord.Snowflake `json:"roles,omitempty"` // Only for whoRoles
+}
+
+// controlPolicies are the policies of a guild for each widget operation.
+type controlPolicies struct {
+	Delete   controlPolicy `json:"delete"`
+	Navigate controlPolicy `json:"navigate"`
+}
+
+// Moderators can delete unwanted previews, only the author can navigate.
+var defaultControlPolicies = controlPolicies{
+	Delete:   controlPolicy{Who: whoManagers},
+	Navigate: controlPolicy{Who: whoAuthor},
+}
+
+// The control policies used by all widgets, set up in main.
+var widgetControls *controlPolicyStore
+
+// The parts of a guild member that policies depend on.
+type memberInfo struct {
+	roles       []disgord.Snowflake
+	permissions disgord.PermissionBit
+}
+
+// How long the member info of a user reacting to a widget is reused, so that role and permission
+// changes still apply soon.
+const memberInfoCacheTime = time.Minute
+
+// memberInfoCache holds the member info of users who reacted to a widget, so that every reaction
+// does not need requests to Discord. It is safe for concurrent use.
+type memberInfoCache struct {
+	mu      sync.Mutex
+	members map[disgord.Snowflake]*cachedMemberInfo
+}
+
+type cachedMemberInfo struct {
+	member  *memberInfo
+	fetched time.Time
+}
+
+func newMemberInfoCache() *memberInfoCache {
+	return &memberInfoCache{members: make(map[disgord.Snowflake]*cachedMemberInfo)}
+}
+
+// Returns the cached member info of the user, or calls getMember if there is none or it is stale.
+// Errors are not cached.
+func (c *memberInfoCache) get(
+	userID disgord.Snowflake,
+	getMember func() (*memberInfo, error),
+) (*memberInfo, error) {
+	c.mu.Lock()
+	cached, ok := c.members[userID]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetched) < memberInfoCacheTime {
+		return cached.member, nil
+	}
+	member, err := getMember()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.members[userID] = &cachedMemberInfo{member, time.Now()}
+	c.mu.Unlock()
+	return member, nil
+}
+
+// Returns whether the policy allows a user who is not the author. getMember is only called if the
+// policy depends on the member.
+func (p *controlPolicy) allows(getMember func() (*memberInfo, error)) (bool, error) {
+	switch p.Who {
+	case whoAnyone:
+		return true, nil
+	case whoManagers:
+		member, err := getMember()
+		if err != nil {
+			return false, err
+		}
+		return member.permissions.Contains(disgord.PermissionManageMessages) ||
+			member.permissions.Contains(disgord.PermissionAdministrator), nil
+	case whoRoles:
+		member, err := getMember()
+		if err != nil {
+			return false, err
+		}
+		for _, role := range member.roles {
+			for _, allowedRole := range p.Roles {
+				if role == allowedRole {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func (p *controlPolicy) String() string {
+	switch p.Who {
+	case whoAnyone:
+		return "Anyone"
+	case whoManagers:
+		return "Author and members with Manage Messages"
+	case whoRoles:
+		var mentions []string
+		for _, role := range p.Roles {
+			mentions = append(mentions, fmt.Sprintf("<@&%v>", role))
+		}
+		return "Author and members with roles " + strings.Join(mentions, " ")
+	}
+	return "Author"
+}
+
+// Parses a policy from command args like ["roles", "<@&1234>", "5678"].
+func parseControlPolicy(args []string) (*controlPolicy, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Missing who may control, expected one of %v, %v, %v or %v",
+			whoAuthor, whoAnyone, whoManagers, whoRoles)
+	}
+	policy := controlPolicy{Who: args[0]}
+	switch policy.Who {
+	case whoAuthor, whoAnyone, whoManagers:
+		if len(args) > 1 {
+			return nil, fmt.Errorf("Unexpected args after %v", policy.Who)
+		}
+	case whoRoles:
+		if len(args) == 1 {
+			return nil, fmt.Errorf("Missing roles after %v", policy.Who)
+		}
+		for _, arg := range args[1:] {
+			roleID, err := strconv.ParseUint(
+				strings.TrimSuffix(strings.TrimPrefix(arg, "<@&"), ">"), 10, 64)
+			if err != nil || roleID == 0 {
+				return nil, fmt.Errorf("Invalid role %v, expected a role mention or ID", arg)
+			}
+			policy.Roles = append(policy.Roles, disgord.Snowflake(roleID))
+		}
+	default:
+		return nil, fmt.Errorf("Unknown %q, expected one of %v, %v, %v or %v",
+			policy.Who, whoAuthor, whoAnyone, whoManagers, whoRoles)
+	}
+	return &policy, nil
+}
+
+// controlPolicyStore holds the control policies of guilds that changed the defaults, optionally
+// saved to a JSON file. It is safe for concurrent use.
+type controlPolicyStore struct {
+	path     string
+	mu       sync.Mutex
+	policies map[disgord.Snowflake]controlPolicies
+}
+
+// Returns a store backed by the file at the given path, or kept only in memory if the path is
+// empty. Existing policies are loaded from the file.
+func newControlPolicyStore(path string) (*controlPolicyStore, error) {
+	s := controlPolicyStore{
+		path:     path,
+		policies: make(map[disgord.Snowflake]controlPolicies),
+	}
+	if path == "" {
+		return &s, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &s.policies); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *controlPolicyStore) get(guildID disgord.Snowflake) controlPolicies {
+	if s == nil {
+		return defaultControlPolicies
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if policies, ok := s.policies[guildID]; ok {
+		return policies
+	}
+	return defaultControlPolicies
+}
+
+func (s *controlPolicyStore) set(guildID disgord.Snowflake, policies controlPolicies) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies[guildID] = policies
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.policies)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename so that a crash midway doesn't corrupt the file.
+	tmp := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Returns whether the user may perform the operation on the widget triggered by ctx.Message.
+// Member info is looked up in the cache of the widget.
+func allowWidgetOp(
+	ctx *bot.Context,
+	members *memberInfoCache,
+	op bot.WidgetOp,
+	userID disgord.Snowflake,
+) bool {
+	// The author can always control the widget, and is the only one who can in DMs.
+	if userID == ctx.Message.Author.ID {
+		return true
+	}
+	if ctx.Message.GuildID.IsZero() {
+		return false
+	}
+	policies := widgetControls.get(ctx.Message.GuildID)
+	policy := &policies.Navigate
+	if op == bot.OpDelete {
+		policy = &policies.Delete
+	}
+	allowed, err := policy.allows(func() (*memberInfo, error) {
+		return members.get(userID, func() (*memberInfo, error) {
+			return getMemberInfo(ctx.Session, ctx.Message.GuildID, userID)
+		})
+	})
+	if err != nil {
+		ctx.Logger.Error(fmt.Errorf("Error checking widget control for user %v: %w", userID, err))
+	}
+	return allowed
+}
+
+func getMemberInfo(
+	session disgord.Session,
+	guildID disgord.Snowflake,
+	userID disgord.Snowflake,
+) (*memberInfo, error) {
+	memberQuery := session.Guild(guildID).Member(userID).WithContext(context.Background())
+	member, err := memberQuery.Get()
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := memberQuery.GetPermissions()
+	if err != nil {
+		return nil, err
+	}
+	return &memberInfo{roles: member.Roles, permissions: permissions}, nil
+}
+
+func onControls(ctx *bot.Context) {
+	go func() {
+		guildID := ctx.Message.GuildID
+		if guildID.IsZero() {
+			ctx.Send("Preview controls can only be configured in a server")
+			return
+		}
+		if len(ctx.Args) == 1 {
+			ctx.Send(makeControlsEmbed(widgetControls.get(guildID)))
+			return
+		}
+		if len(ctx.Args) < 3 {
+			ctx.SendIncorrectUsageMsg()
+			return
+		}
+
+		member, err := getMemberInfo(ctx.Session, guildID, ctx.Message.Author.ID)
+		if err != nil {
+			err = fmt.Errorf("Error checking permissions: %w", err)
+			ctx.Logger.Error(err)
+			respondWithError(ctx, err)
+			return
+		}
+		if !member.permissions.Contains(disgord.PermissionManageServer) &&
+			!member.permissions.Contains(disgord.PermissionAdministrator) {
+			ctx.Send("Only members with the Manage Server permission can configure preview controls")
+			return
+		}
+
+		policy, err := parseControlPolicy(ctx.Args[2:])
+		if err != nil {
+			respondWithError(ctx, err)
+			return
+		}
+		policies := widgetControls.get(guildID)
+		switch ctx.Args[1] {
+		case "delete":
+			policies.Delete = *policy
+		case "navigate":
+			policies.Navigate = *policy
+		default:
+			ctx.SendIncorrectUsageMsg()
+			return
+		}
+		if err = widgetControls.set(guildID, policies); err != nil {
+			err = fmt.Errorf("Error saving preview controls: %w", err)
+			ctx.Logger.Error(err)
+			respondWithError(ctx, err)
+			return
+		}
+		ctx.Send(makeControlsEmbed(policies))
+	}()
+}
+
+func makeControlsEmbed(policies controlPolicies) *disgord.Embed {
+	return &disgord.Embed{
+		Author: &disgord.EmbedAuthor{Name: "Preview controls"},
+		Fields: []*disgord.EmbedField{
+			{Name: "Delete", Value: policies.Delete.String()},
+			{Name: "Navigate", Value: policies.Navigate.String()},
+		},
+	}
+}
+
+// Installs the controls command.
+func installControlsCommand(b *bot.Bot) {
+	b.Client.Logger().Info("Setting up controls command")
+	b.AddCommand(&bot.Command{
+		ID:          "controls",
+		Usage:       "[delete|navigate author|anyone|managers|roles <role>...]",
+		Description: "Shows or sets who can delete and navigate previews in this server",
+		Handler:     onControls,
+	})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andersfylling/disgord"
+	"github.com/go-test/deep"
+)
+
+func TestControlPolicyAllows(t *testing.T) {
+	manager := &memberInfo{permissions: disgord.PermissionManageMessages}
+	admin := &memberInfo{permissions: disgord.PermissionAdministrator}
+	withRole := &memberInfo{roles: []disgord.Snowflake{7564321, 7564322}}
+	nobody := &memberInfo{}
+	rolesPolicy := &controlPolicy{Who: whoRoles, Roles: []disgord.Snowflake{7564322, 7564323}}
+
+	for _, test := range []struct {
+		policy *controlPolicy
+		member *memberInfo
+		want   bool
+	}{
+		{&controlPolicy{Who: whoAuthor}, manager, false},
+		{&controlPolicy{Who: whoAnyone}, nobody, true},
+		{&controlPolicy{Who: whoManagers}, manager, true},
+		{&controlPolicy{Who: whoManagers}, admin, true},
+		{&controlPolicy{Who: whoManagers}, withRole, false},
+		{rolesPolicy, withRole, true},
+		{rolesPolicy, manager, false},
+		{rolesPolicy, nobody, false},
+	} {
+		got, err := test.policy.allows(func() (*memberInfo, error) { return test.member, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%v for %+v: got %v, want %v", test.policy.Who, test.member, got, test.want)
+		}
+	}
+
+	// The member is not fetched when not required, and errors are returned.
+	getMemberErr := errors.New("member not found")
+	getMember := func() (*memberInfo, error) { return nil, getMemberErr }
+	if got, err := (&controlPolicy{Who: whoAnyone}).allows(getMember); !got || err != nil {
+		t.Errorf("anyone: got %v, %v, want true, nil", got, err)
+	}
+	if got, err := rolesPolicy.allows(getMember); got || err != getMemberErr {
+		t.Errorf("roles: got %v, %v, want false, %v", got, err, getMemberErr)
+	}
+}
+
+func TestMemberInfoCache(t *testing.T) {
+	cache := newMemberInfoCache()
+	member := &memberInfo{permissions: disgord.PermissionManageMessages}
+	calls := 0
+	getMember := func() (*memberInfo, error) {
+		calls++
+		return member, nil
+	}
+	for i := 0; i < 3; i++ {
+		if got, err := cache.get(1, getMember); got != member || err != nil {
+			t.Fatalf("got %v, %v, want the member", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("got %v calls, want 1", calls)
+	}
+
+	// Errors are not cached, and other users are fetched separately.
+	getMemberErr := errors.New("member not found")
+	failing := func() (*memberInfo, error) { return nil, getMemberErr }
+	if _, err := cache.get(2, failing); err != getMemberErr {
+		t.Fatalf("got error %v, want %v", err, getMemberErr)
+	}
+	if got, err := cache.get(2, getMember); got != member || err != nil || calls != 2 {
+		t.Fatalf("got %v, %v after %v calls, want the member after 2", got, err, calls)
+	}
+}
+
+func TestParseControlPolicy(t *testing.T) {
+	for _, test := range []struct {
+		args    []string
+		want    *controlPolicy
+		wantErr bool
+	}{
+		{[]string{"author"}, &controlPolicy{Who: whoAuthor}, false},
+		{[]string{"anyone"}, &controlPolicy{Who: whoAnyone}, false},
+		{[]string{"managers"}, &controlPolicy{Who: whoManagers}, false},
+		{
+			[]string{"roles", "<@&7564321>", "7564322"},
+			&controlPolicy{Who: whoRoles, Roles: []disgord.Snowflake{7564321, 7564322}},
+			false,
+		},
+		{nil, nil, true},
+		{[]string{"everyone"}, nil, true},
+		{[]string{"anyone", "else"}, nil, true},
+		{[]string{"roles"}, nil, true},
+		{[]string{"roles", "@Moderator"}, nil, true},
+	} {
+		got, err := parseControlPolicy(test.args)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want error %v", test.args, err, test.wantErr)
+		}
+		if diff := deep.Equal(got, test.want); diff != nil {
+			t.Errorf("%v: %v", test.args, diff)
+		}
+	}
+}
+
+func TestControlPolicyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "controls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "controls.json")
+
+	store, err := newControlPolicyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guildID := disgord.Snowflake(6543217)
+	if diff := deep.Equal(store.get(guildID), defaultControlPolicies); diff != nil {
+		t.Fatal(diff)
+	}
+
+	policies := controlPolicies{
+		Delete:   controlPolicy{Who: whoAnyone},
+		Navigate: controlPolicy{Who: whoRoles, Roles: []disgord.Snowflake{7564321}},
+	}
+	if err = store.set(guildID, policies); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store reads what the old one wrote.
+	store, err = newControlPolicyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(store.get(guildID), policies); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(store.get(6543218), defaultControlPolicies); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/meooow25/cfspy/bot"
+)
+
+// Kinds of diff edits.
+const (
+	diffEqual = iota
+	diffDelete
+	diffInsert
+)
+
+// An edit turning one sequence into another. a is the index in the old sequence for equal and
+// deleted elements, b is the index in the new sequence for equal and inserted elements.
+type diffEdit struct {
+	kind int
+	a, b int
+}
+
+// Above this many cells the changed middle of two sequences is not diffed but treated as entirely
+// replaced, to bound time and memory.
+const maxDiffCells = 1 << 22
+
+// Returns a shortest edit script turning a into b, from a longest common subsequence. Deletions
+// come before insertions where both are possible.
+func diffSlices(a, b []string) []diffEdit {
+	var edits []diffEdit
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		edits = append(edits, diffEdit{diffEqual, prefix, prefix})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	n, m := len(a)-prefix-suffix, len(b)-prefix-suffix
+
+	if n*m > maxDiffCells {
+		for i := 0; i < n; i++ {
+			edits = append(edits, diffEdit{diffDelete, prefix + i, -1})
+		}
+		for j := 0; j < m; j++ {
+			edits = append(edits, diffEdit{diffInsert, -1, prefix + j})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of the middles from i and j.
+		lcs := make([][]int32, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				switch {
+				case a[prefix+i] == b[prefix+j]:
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				case lcs[i+1][j] >= lcs[i][j+1]:
+					lcs[i][j] = lcs[i+1][j]
+				default:
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && a[prefix+i] == b[prefix+j]:
+				edits = append(edits, diffEdit{diffEqual, prefix + i, prefix + j})
+				i++
+				j++
+			case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+				edits = append(edits, diffEdit{diffDelete, prefix + i, -1})
+				i++
+			default:
+				edits = append(edits, diffEdit{diffInsert, -1, prefix + j})
+				j++
+			}
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		edits = append(edits, diffEdit{diffEqual, len(a) - suffix + k, len(b) - suffix + k})
+	}
+	return edits
+}
+
+// Returns the new markdown text with the changes from the old text marked, removed words struck
+// through and inserted words in bold. Links are marked in their text so that they stay links. Code
+// is never marked inside, changed code spans are marked whole and changed code blocks are shown as
+// a diff of their lines. Whitespace of the new text is kept, only the tokens are compared.
+func diffWordsMarkdown(oldText, newText string) string {
+	oldTokens := bot.TokenizeMarkdown(oldText)
+	newTokens := bot.TokenizeMarkdown(newText)
+	oldWords := make([]string, len(oldTokens))
+	for i, token := range oldTokens {
+		oldWords[i] = oldText[token.Start:token.End]
+	}
+	newWords := make([]string, len(newTokens))
+	for i, token := range newTokens {
+		newWords[i] = newText[token.Start:token.End]
+	}
+	edits := diffSlices(oldWords, newWords)
+
+	var b strings.Builder
+	pos := 0 // End of the new text written
+	needSpace := false
+	needLine := false // After a code block
+	// Writes the whitespace before the new token at index j.
+	writeSpaceBefore := func(j int) {
+		space := newText[pos:newTokens[j].Start]
+		if needLine && !strings.Contains(space, "\n") {
+			space = "\n"
+		} else if space == "" && needSpace {
+			space = " "
+		}
+		b.WriteString(space)
+		needSpace, needLine = false, false
+	}
+	// Writes a code block diff, which must be on lines of its own.
+	writeCodeBlock := func(oldLines, newLines []string) {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString(codeDiffBlock(oldLines, newLines))
+		needSpace, needLine = false, true
+	}
+	// Writes removed words right after the previous word, before the whitespace of the new text.
+	writeDeleted := func(deleted []int) {
+		var words []string
+		write := func(text string) {
+			if b.Len() > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(text)
+			needSpace = true
+		}
+		flush := func() {
+			if len(words) > 0 {
+				write("~~" + strings.Join(words, " ") + "~~")
+				words = nil
+			}
+		}
+		for _, i := range deleted {
+			switch oldTokens[i].Kind {
+			case bot.MarkdownCodeBlock:
+				flush()
+				writeCodeBlock(codeBlockLines(oldWords[i]), nil)
+			case bot.MarkdownLink:
+				flush()
+				write(markLink(oldWords[i], "~~"))
+			default:
+				words = append(words, oldWords[i])
+			}
+		}
+		flush()
+	}
+
+	for start := 0; start < len(edits); {
+		end := start
+		if edits[start].kind == diffEqual {
+			for end < len(edits) && edits[end].kind == diffEqual {
+				end++
+			}
+			first, last := edits[start].b, edits[end-1].b
+			writeSpaceBefore(first)
+			b.WriteString(newText[newTokens[first].Start:newTokens[last].End])
+			pos = newTokens[last].End
+			start = end
+			continue
+		}
+
+		var deleted, inserted []int
+		for ; end < len(edits) && edits[end].kind != diffEqual; end++ {
+			if edits[end].kind == diffDelete {
+				deleted = append(deleted, edits[end].a)
+			} else {
+				inserted = append(inserted, edits[end].b)
+			}
+		}
+		// Code blocks replaced by others are shown as a diff from the old block, in place of the
+		// new one.
+		replaced := make(map[int]int) // New code block to old
+		var unpaired []int
+		var insertedBlocks []int
+		for _, j := range inserted {
+			if newTokens[j].Kind == bot.MarkdownCodeBlock {
+				insertedBlocks = append(insertedBlocks, j)
+			}
+		}
+		for _, i := range deleted {
+			if oldTokens[i].Kind == bot.MarkdownCodeBlock && len(insertedBlocks) > 0 {
+				replaced[insertedBlocks[0]] = i
+				insertedBlocks = insertedBlocks[1:]
+				continue
+			}
+			unpaired = append(unpaired, i)
+		}
+		writeDeleted(unpaired)
+
+		for k := 0; k < len(inserted); {
+			j := inserted[k]
+			writeSpaceBefore(j)
+			switch newTokens[j].Kind {
+			case bot.MarkdownCodeBlock:
+				var oldLines []string
+				if i, ok := replaced[j]; ok {
+					oldLines = codeBlockLines(oldWords[i])
+				}
+				writeCodeBlock(oldLines, codeBlockLines(newWords[j]))
+				k++
+			case bot.MarkdownLink:
+				b.WriteString(markLink(newWords[j], "**"))
+				k++
+			default:
+				last := k
+				for last+1 < len(inserted) && newTokens[inserted[last+1]].Kind != bot.MarkdownLink &&
+					newTokens[inserted[last+1]].Kind != bot.MarkdownCodeBlock {
+					last++
+				}
+				b.WriteString(markLines(newText[newTokens[j].Start:newTokens[inserted[last]].End], "**"))
+				k = last + 1
+			}
+			pos = newTokens[inserted[k-1]].End
+		}
+		start = end
+	}
+	return b.String()
+}
+
+// Returns the link with its text wrapped in the markdown delimiter, or the whole link if the text
+// is blank.
+func markLink(link, delim string) string {
+	textStart := strings.IndexByte(link, '[') + 1
+	textEnd := strings.Index(link, "](")
+	if strings.TrimSpace(link[textStart:textEnd]) == "" {
+		return delim + link + delim
+	}
+	return link[:textStart] + markLines(link[textStart:textEnd], delim) + link[textEnd:]
+}
+
+// Returns the lines of code in a markdown code block, without the fences and the language.
+func codeBlockLines(block string) []string {
+	code := strings.TrimSuffix(strings.TrimPrefix(block, "```"), "```")
+	// The opening line has the language, if any.
+	if i := strings.IndexByte(code, '\n'); i != -1 {
+		code = code[i+1:]
+	}
+	code = strings.TrimSuffix(code, "\n")
+	if code == "" {
+		return nil
+	}
+	return strings.Split(code, "\n")
+}
+
+// Returns a markdown diff code block with the lines turning the old lines into the new lines,
+// prefixed by "-" if removed, "+" if inserted and " " if unchanged.
+func codeDiffBlock(oldLines, newLines []string) string {
+	lines := []string{"```diff"}
+	for _, edit := range diffSlices(oldLines, newLines) {
+		switch edit.kind {
+		case diffEqual:
+			lines = append(lines, " "+newLines[edit.b])
+		case diffDelete:
+			lines = append(lines, "-"+oldLines[edit.a])
+		case diffInsert:
+			lines = append(lines, "+"+newLines[edit.b])
+		}
+	}
+	return strings.Join(append(lines, "```"), "\n")
+}
+
+// Wraps each non-blank line of text in the markdown delimiter, since inline formatting cannot span
+// paragraphs.
+func markLines(text, delim string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		lead := line[:strings.Index(line, trimmed)]
+		lines[i] = lead + delim + trimmed + delim
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns a unified diff turning the lines of a into the lines of b, with hunks having contextLines
+// lines of context around changes. The diff has no file headers. Returns "" if there are no
+// changes.
+func unifiedDiff(a, b []string, contextLines int) string {
+	edits := diffSlices(a, b)
+	var changes []int
+	for k, edit := range edits {
+		if edit.kind != diffEqual {
+			changes = append(changes, k)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var lines []string
+	aDone, bDone := 0, 0 // Lines of a and b before the edit at k
+	k := 0
+	for i := 0; i < len(changes); {
+		start := changes[i] - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := changes[i] + contextLines + 1
+		for i++; i < len(changes) && changes[i]-contextLines <= end; i++ {
+			end = changes[i] + contextLines + 1
+		}
+		if end > len(edits) {
+			end = len(edits)
+		}
+		for ; k < start; k++ {
+			aDone, bDone = advanceDiff(edits[k], aDone, bDone)
+		}
+		var hunk []string
+		aStart, bStart := aDone, bDone
+		for ; k < end; k++ {
+			switch edits[k].kind {
+			case diffEqual:
+				hunk = append(hunk, " "+a[edits[k].a])
+			case diffDelete:
+				hunk = append(hunk, "-"+a[edits[k].a])
+			case diffInsert:
+				hunk = append(hunk, "+"+b[edits[k].b])
+			}
+			aDone, bDone = advanceDiff(edits[k], aDone, bDone)
+		}
+		lines = append(lines, fmt.Sprintf("@@ -%v +%v @@",
+			hunkRange(aStart, aDone-aStart), hunkRange(bStart, bDone-bStart)))
+		lines = append(lines, hunk...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns the counts of lines of the old and new sequences done after the edit.
+func advanceDiff(edit diffEdit, aDone, bDone int) (int, int) {
+	if edit.kind != diffInsert {
+		aDone++
+	}
+	if edit.kind != diffDelete {
+		bDone++
+	}
+	return aDone, bDone
+}
+
+// Returns the range of a hunk header, like "5,3" for 3 lines after the first 4. An empty range is
+// given by the line before it.
+func hunkRange(done, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%v,0", done)
+	}
+	if count == 1 {
+		return fmt.Sprint(done + 1)
+	}
+	return fmt.Sprintf("%v,%v", done+1, count)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffWordsMarkdown(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"same", "a b  c", "a b  c", "a b  c"},
+		{"whitespace only", "a b c", "a\nb  c", "a\nb  c"},
+		{"insert", "a c", "a b c", "a **b** c"},
+		{"delete", "a b c", "a c", "a ~~b~~ c"},
+		{"replace", "a b c d", "a x y d", "a ~~b c~~ **x y** d"},
+		{"delete first", "a b", "b", "~~a~~ b"},
+		{"delete last", "a b", "a", "a ~~b~~"},
+		{"insert lines", "a", "a\n\nb c\nd", "a\n\n**b c**\n**d**"},
+		{"empty old", "", "a b", "**a b**"},
+		{"empty new", "a b", "", "~~a b~~"},
+		{
+			"code block changed",
+			"a\n```cpp\nint x = 1;\nint y;\n```",
+			"a\n```cpp\nint x = 5;\nint y;\n```",
+			"a\n```diff\n-int x = 1;\n+int x = 5;\n int y;\n```",
+		},
+		{"code block deleted", "a\n```\ncode\n```\nb", "a b", "a\n```diff\n-code\n```\nb"},
+		{"code block inserted", "a b", "a\n```\ncode\n```\nb", "a\n```diff\n+code\n```\nb"},
+		{"code span", "run `a b` now", "run `a c` now", "run ~~`a b`~~ **`a c`** now"},
+		{
+			"link",
+			"see [the link](https://a.com/x)",
+			"see [the link](https://a.com/y)",
+			"see [~~the link~~](https://a.com/x) [**the link**](https://a.com/y)",
+		},
+		{"link text", "[a](u) b", "[c](u) b", "[~~a~~](u) [**c**](u) b"},
+	} {
+		if got := diffWordsMarkdown(test.old, test.new); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiffSlicesLarge(t *testing.T) {
+	a := make([]string, 3000)
+	b := make([]string, 3000)
+	for i := range a {
+		a[i] = "a"
+		b[i] = "b"
+	}
+	edits := diffSlices(a, b)
+	if len(edits) != len(a)+len(b) {
+		t.Fatalf("got %v edits, want %v", len(edits), len(a)+len(b))
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, " ")
+	}
+	for _, test := range []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"same", "a b c", "a b c", ""},
+		{"change", "a b c d e f g h", "a b c d x f g h",
+			"@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+x\n f\n g\n h"},
+		{"insert at start", "a b", "x a b", "@@ -1,2 +1,3 @@\n+x\n a\n b"},
+		{"delete at end", "a b c", "a b", "@@ -1,3 +1,2 @@\n a\n b\n-c"},
+		{"from empty", "", "a", "@@ -0,0 +1 @@\n+a"},
+		{"merged hunks", "1 2 3 4 5 6 7 8 9", "1 x 3 4 5 6 7 y 9",
+			"@@ -1,9 +1,9 @@\n 1\n-2\n+x\n 3\n 4\n 5\n 6\n 7\n-8\n+y\n 9"},
+		{"separate hunks", "1 2 3 4 5 6 7 8 9 10 11 12", "x 2 3 4 5 6 7 8 9 10 11 y",
+			"@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+y"},
+	} {
+		if got := unifiedDiff(split(test.old), split(test.new), 3); got != test.want {
+			t.Errorf("%v: got\n%v\nwant\n%v", test.name, got, test.want)
+		}
+	}
+}
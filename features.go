@@ -10,12 +10,16 @@ const featureInfo = "CFSpy watches for Codeforces links and shows helpful previe
 	"- _Blogs_: Shows the blog information and content.\n" +
 	"- _Comments_: Shows the comment information and content.\n" +
 	"- _Problems_: Shows some information about the problem.\n" +
-	"- _Profiles_: Shows some information about the user profile.\n" +
+	"- _Profiles_: Shows the rating, contribution, organization, location and activity of the user.\n" +
 	"- _Submissions_: Shows some information about the submission.\n" +
 	"- _Submissions with line numbers_: Shows a snippet from the submission containing the " +
 	"specified lines. Install this " +
 	"[userscript](https://greasyfork.org/en/scripts/403747-cf-linemaster) to get line selection " +
-	"and highlighting support in your browser.\n\n" +
+	"and highlighting support in your browser.\n" +
+	"- _Two submissions_: Shows a diff between the two submissions, also available with the diff " +
+	"command.\n\n" +
+	"To jump to a page of a preview with many pages, reply to the preview with the page number.\n\n" +
+	"Who can delete and navigate previews is configured per server with the controls command.\n\n" +
 	"To make CFSpy ignore links wrap them in < >, this is also how Discord's default embeds work."
 
 func onFeatureInfo(ctx *bot.Context) {
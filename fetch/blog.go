@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
@@ -31,40 +33,122 @@ var (
 	revisionCountAttr  = "revisioncount"
 	revisionSpanSelec  = cascadia.MustCompile("span[" + revisionCountAttr + "]")
 	csrfTokenSelec     = cascadia.MustCompile(`meta[name="X-Csrf-Token"]`)
+	iconSrcRe          = regexp.MustCompile(`(?i)/(flags|icons?)/|emoji|smile`)
+	styleSizeRe        = regexp.MustCompile(`(?:^|;)\s*(?:max-)?(width|height)\s*:\s*(\d+)px`)
+	blogTagSelec       = cascadia.MustCompile("a.tag")
+	blogCommentsSelec  = cascadia.MustCompile(`.right-meta a[href$="#comments"]`)
+	blogHistorySelec   = cascadia.MustCompile(`.info a[href^="/topic/"]`)
+	blogContestSelec   = cascadia.MustCompile(".meta a")
+	sidebarLinkSelec   = cascadia.MustCompile("#sidebar .caption a")
+	blogRevisionRe     = regexp.MustCompile(`^/topic/(\d+)/([a-z]+)(\d+)$`)
+	contestPathRe      = regexp.MustCompile(`^(?:https?://codeforces\.com)?/(?:contest|gym)/\d+/?$`)
 )
 
 // Blog fetches blog information using the DefaultFetcher.
-func Blog(ctx context.Context, url string) (*BlogInfo, error) {
+func Blog(
+	ctx context.Context,
+	url string,
+) (revisionCount int, getter BlogInfoGetter, err error) {
 	return DefaultFetcher.Blog(ctx, url)
 }
 
-// Blog fetches blog information. The given URL must be a valid blog URL.
+// BlogInfoGetter is a function that returns the blog info for a given revision. It is safe for
+// concurrent use.
+type BlogInfoGetter func(revision int) (*BlogInfo, error)
+
+// Blog fetches blog information. The given URL must be a valid blog URL. A BlogInfoGetter is
+// returned. The last revision is immediately available, other revisions are fetched lazily when
+// the BlogInfoGetter is called.
 //
 // Scrapes instead of using the API because a preview will be added but the blog content is not
 // available through the API.
-func (f *Fetcher) Blog(ctx context.Context, url string) (*BlogInfo, error) {
+func (f *Fetcher) Blog(
+	ctx context.Context,
+	url string,
+) (revisionCount int, getter BlogInfoGetter, err error) {
+	latest, rev, err := f.fetchBlog(ctx, url)
+	if err != nil {
+		return
+	}
+
+	revisionCount = latest.RevisionCount
+	cache := map[int]*BlogInfo{revisionCount: latest}
+	var cacheMu sync.Mutex
+
+	getter = func(revision int) (*BlogInfo, error) {
+		if revision <= 0 || revision > revisionCount {
+			return nil, fmt.Errorf(
+				"Expected revision between 1 and %v, got %v", revisionCount, revision)
+		}
+		cacheMu.Lock()
+		defer cacheMu.Unlock()
+		if _, ok := cache[revision]; !ok {
+			doc, err := f.FetchPage(ctx, rev.url(revision))
+			if err != nil {
+				return nil, err
+			}
+			cur := *latest
+			cur.Revision = revision
+			if title := strings.TrimSpace(doc.FindMatcher(titleSelec).First().Text()); title != "" {
+				cur.Title = title
+			}
+			cur.Content, cur.Images, cur.Math = getContentAsMarkdown(
+				doc.FindMatcher(typographySelec).First())
+			cache[revision] = &cur
+		}
+		return cache[revision], nil
+	}
+	return
+}
+
+// Fetches the latest revision of the blog, and the link to it if there are older revisions.
+func (f *Fetcher) fetchBlog(ctx context.Context, url string) (*BlogInfo, blogRevisionLink, error) {
+	var rev blogRevisionLink
 	doc, err := f.FetchPage(ctx, url)
 	if err != nil {
-		return nil, err
+		return nil, rev, err
 	}
 
 	var b BlogInfo
 	b.URL = url
 	b.Title = strings.TrimSpace(doc.FindMatcher(titleSelec).First().Text())
 	blogDiv := doc.FindMatcher(blogSelec)
-	b.Content, b.Images = getContentAsMarkdown(blogDiv.FindMatcher(typographySelec).First())
+	b.Content, b.Images, b.Math = getContentAsMarkdown(blogDiv.FindMatcher(typographySelec).First())
 	b.AuthorHandle, b.AuthorColor = parseHandleAndColor(blogDiv)
 	if b.CreationTime, err = parseTime(blogDiv); err != nil {
-		return nil, err
+		return nil, rev, err
 	}
 	ratingSpan := blogDiv.FindMatcher(blogRatingSelec)
 	if ratingSpan.Length() == 0 {
 		ratingSpan = blogDiv.FindMatcher(blogRatingRuSelec)
 	}
 	if b.Rating, err = strconv.Atoi(ratingSpan.Text()); err != nil {
-		return nil, fmt.Errorf("Error getting blog rating: %w", err)
+		return nil, rev, fmt.Errorf("Error getting blog rating: %w", err)
 	}
 
+	blogDiv.FindMatcher(blogTagSelec).Each(func(_ int, tag *goquery.Selection) {
+		b.Tags = append(b.Tags, strings.TrimSpace(tag.Text()))
+	})
+	// Missing if comments are closed.
+	if cnt, err := strconv.Atoi(strings.TrimSpace(
+		blogDiv.FindMatcher(blogCommentsSelec).Last().Text())); err == nil {
+		b.CommentCount = cnt
+	}
+	b.Contest = parseBlogContest(doc, blogDiv)
+
+	// The history link is to the latest revision, and only shown if there are older ones. The time
+	// of the last edit is only on the revision page, which is fetched while the rest is done.
+	b.RevisionCount = 1
+	lastEditTime := make(chan time.Time, 1)
+	var ok bool
+	if rev, ok = parseBlogRevisionLink(blogDiv); ok {
+		b.RevisionCount = rev.number
+		go func() { lastEditTime <- f.fetchBlogRevisionTime(ctx, rev) }()
+	} else {
+		lastEditTime <- time.Time{}
+	}
+	b.Revision = b.RevisionCount
+
 	// If the author commented under the blog get the pic, otherwise fetch from the API.
 	if authorCommentAvatars := blogDiv.FindMatcher(commentAvatarSelec).FilterFunction(
 		func(_ int, s *goquery.Selection) bool {
@@ -76,11 +160,75 @@ func (f *Fetcher) Blog(ctx context.Context, url string) (*BlogInfo, error) {
 		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 		if b.AuthorAvatar, err = f.FetchAvatar(ctx, b.AuthorHandle); err != nil {
-			return nil, err
+			return nil, rev, err
 		}
 	}
 
-	return &b, nil
+	if t := <-lastEditTime; t.After(b.CreationTime) {
+		b.LastEditTime = t
+	}
+	return &b, rev, nil
+}
+
+// Returns the time of the latest revision of a blog from the revision page, or zero if it cannot
+// be fetched.
+func (f *Fetcher) fetchBlogRevisionTime(ctx context.Context, rev blogRevisionLink) time.Time {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	doc, err := f.FetchPage(ctx, rev.url(rev.number))
+	if err != nil {
+		return time.Time{}
+	}
+	t, err := parseTime(doc.FindMatcher(blogSelec))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// A link to a revision of a blog, like /topic/87977/ru11. Revisions are numbered from 1 per
+// locale.
+type blogRevisionLink struct {
+	topicID string
+	locale  string
+	number  int
+}
+
+func parseBlogRevisionLink(blogDiv *goquery.Selection) (link blogRevisionLink, ok bool) {
+	match := blogRevisionRe.FindStringSubmatch(blogDiv.FindMatcher(blogHistorySelec).AttrOr("href", ""))
+	if match == nil {
+		return
+	}
+	number, err := strconv.Atoi(match[3])
+	if err != nil {
+		return
+	}
+	return blogRevisionLink{topicID: match[1], locale: match[2], number: number}, true
+}
+
+// Returns the URL of the given revision of the blog.
+func (l blogRevisionLink) url(revision int) string {
+	return fmt.Sprintf("https://codeforces.com/topic/%v/%v%v", l.topicID, l.locale, revision)
+}
+
+// Returns the contest an announcement or tutorial blog is for, from the links under the blog or in
+// the sidebar, or nil if there is none. Links to the contests page, like the upcoming contest in
+// the sidebar, are not to a contest.
+func parseBlogContest(doc *goquery.Document, blogDiv *goquery.Selection) *BlogContest {
+	var contest *BlogContest
+	blogDiv.FindMatcher(blogContestSelec).AddSelection(doc.FindMatcher(sidebarLinkSelec)).EachWithBreak(
+		func(_ int, a *goquery.Selection) bool {
+			href := a.AttrOr("href", "")
+			if !contestPathRe.MatchString(href) {
+				return true
+			}
+			contest = &BlogContest{
+				Name: strings.TrimSpace(a.Text()),
+				URL:  strings.TrimSuffix(withCodeforcesHost(href), "/"),
+			}
+			return false
+		})
+	return contest
 }
 
 // Comment fetches comment information using the DefaultFetcher.
@@ -92,7 +240,8 @@ func Comment(
 	return DefaultFetcher.Comment(ctx, url, commentID)
 }
 
-// CommentInfoGetter is a function that returns the comment info for a given revision.
+// CommentInfoGetter is a function that returns the comment info for a given revision. It is safe
+// for concurrent use.
 type CommentInfoGetter func(revision int) (*CommentInfo, error)
 
 // Comment fetches comment information. The given URL must be a valid comment URL. A
@@ -113,47 +262,52 @@ func (f *Fetcher) Comment(
 	if err != nil {
 		return
 	}
-	comment := doc.Find(fmt.Sprintf(`[commentId="%v"]`, commentID))
+	comment := findComment(doc, commentID)
 	if comment.Length() == 0 {
 		err = fmt.Errorf("No comment with ID %v found", commentID)
 		return
 	}
 
-	var base CommentInfo
-	base.URL = url
-	base.BlogTitle = strings.TrimSpace(doc.FindMatcher(titleSelec).First().Text())
-	avatarDiv := comment.FindMatcher(commentAvatarSelec)
-	if base.CreationTime, err = parseTime(comment); err != nil {
+	latest, err := parseComment(doc, comment, url)
+	if err != nil {
 		return
 	}
-	base.AuthorHandle, base.AuthorColor = parseHandleAndColor(avatarDiv)
-	base.AuthorAvatar = parseImg(avatarDiv)
-	if base.Rating, err = strconv.Atoi(comment.FindMatcher(commentRatingSelec).Text()); err != nil {
-		return
+	latest.ReplyCount = doc.Find(fmt.Sprintf(`[commentParentId="%v"]`, commentID)).Length()
+	for parentID := commentParentID(comment); parentID != ""; {
+		parentComment := findComment(doc, parentID)
+		if parentComment.Length() == 0 {
+			break
+		}
+		// Deleted comments cannot be parsed, the thread is shown up to them.
+		parent, parseErr := parseComment(doc, parentComment, commentURL(url, parentID))
+		if parseErr != nil {
+			break
+		}
+		latest.Parents = append(latest.Parents, parent)
+		parentID = commentParentID(parentComment)
 	}
 
 	csrf := doc.FindMatcher(csrfTokenSelec).AttrOr("content", "?!")
-	revisionCount = parseCommentRevision(comment)
-	base.RevisionCount = revisionCount
-
-	latest := base
-	latest.Content, latest.Images = getContentAsMarkdown(comment.FindMatcher(typographySelec))
-	latest.Revision = revisionCount
-	cache := map[int]*CommentInfo{revisionCount: &latest}
+	revisionCount = latest.RevisionCount
+	cache := map[int]*CommentInfo{revisionCount: latest}
+	var cacheMu sync.Mutex
 
 	getter = func(revision int) (*CommentInfo, error) {
-		if revision <= 0 || revision > base.RevisionCount {
+		if revision <= 0 || revision > revisionCount {
 			return nil, fmt.Errorf(
-				"Expected revision between 1 and %v, got %v", base.RevisionCount, revision)
+				"Expected revision between 1 and %v, got %v", revisionCount, revision)
 		}
+		// Held while fetching, revisions are fetched one at a time with the same client.
+		cacheMu.Lock()
+		defer cacheMu.Unlock()
 		if _, ok := cache[revision]; !ok {
 			doc, err := f.FetchCommentRevision(ctx, commentID, revision, csrf, client)
 			if err != nil {
 				return nil, err
 			}
-			cur := base
+			cur := *latest
 			cur.Revision = revision
-			cur.Content, cur.Images = getContentAsMarkdown(doc.FindMatcher(typographySelec))
+			cur.Content, cur.Images, cur.Math = getContentAsMarkdown(doc.FindMatcher(typographySelec))
 			cache[revision] = &cur
 		}
 		return cache[revision], nil
@@ -161,6 +315,53 @@ func (f *Fetcher) Comment(
 	return
 }
 
+// Returns the comment with the given ID in the blog page.
+func findComment(doc *goquery.Document, commentID string) *goquery.Selection {
+	return doc.Find(fmt.Sprintf(`[commentId="%v"]`, commentID))
+}
+
+// Returns the ID of the comment's parent, or "" if it is not a reply.
+func commentParentID(comment *goquery.Selection) string {
+	id := comment.First().AttrOr("commentparentid", "-1")
+	if id == "-1" {
+		return ""
+	}
+	return id
+}
+
+// Returns the URL of the comment with the given ID in the blog page at blogURL.
+func commentURL(blogURL, commentID string) string {
+	if i := strings.IndexByte(blogURL, '#'); i != -1 {
+		blogURL = blogURL[:i]
+	}
+	return blogURL + "#comment-" + commentID
+}
+
+// Parses the latest revision of the comment, without parents and replies.
+func parseComment(
+	doc *goquery.Document,
+	comment *goquery.Selection,
+	url string,
+) (*CommentInfo, error) {
+	var c CommentInfo
+	var err error
+	c.URL = url
+	c.BlogTitle = strings.TrimSpace(doc.FindMatcher(titleSelec).First().Text())
+	avatarDiv := comment.FindMatcher(commentAvatarSelec)
+	if c.CreationTime, err = parseTime(comment); err != nil {
+		return nil, err
+	}
+	c.AuthorHandle, c.AuthorColor = parseHandleAndColor(avatarDiv)
+	c.AuthorAvatar = parseImg(avatarDiv)
+	if c.Rating, err = strconv.Atoi(comment.FindMatcher(commentRatingSelec).Text()); err != nil {
+		return nil, err
+	}
+	c.Content, c.Images, c.Math = getContentAsMarkdown(comment.FindMatcher(typographySelec))
+	c.RevisionCount = parseCommentRevision(comment)
+	c.Revision = c.RevisionCount
+	return &c, nil
+}
+
 func parseCommentRevision(comment *goquery.Selection) int {
 	cnt, err := strconv.Atoi(comment.FindMatcher(revisionSpanSelec).AttrOr(revisionCountAttr, "?!"))
 	if err != nil {
@@ -169,8 +370,11 @@ func parseCommentRevision(comment *goquery.Selection) int {
 	return cnt
 }
 
-func getContentAsMarkdown(selec *goquery.Selection) (markdown string, imgURLs []string) {
+func getContentAsMarkdown(
+	selec *goquery.Selection,
+) (markdown string, imgURLs []string, displayMath []string) {
 	converter := md.NewConverter("", true, nil)
+	converter.AddRules(contentRules...)
 	converter.AddRules(
 		md.Rule{
 			Filter: []string{"img"},
@@ -184,7 +388,9 @@ func getContentAsMarkdown(selec *goquery.Selection) (markdown string, imgURLs []
 					return new(string)
 				}
 				src = withCodeforcesHost(src)
-				imgURLs = append(imgURLs, src)
+				if !isIconImage(selec) {
+					imgURLs = append(imgURLs, src)
+				}
 				text := fmt.Sprintf("[img%s](%s)", alt, src)
 				return &text
 			},
@@ -218,12 +424,35 @@ func getContentAsMarkdown(selec *goquery.Selection) (markdown string, imgURLs []
 	)
 	converter.Use(plugin.Strikethrough("~~"))
 
+	displayMath = convertMath(selec)
 	markdown = converter.Convert(selec)
 
-	// Replace LaTeX delimiter $$$ with $ because it looks ugly.
-	// TODO: Maybe Unicode symbols can be used in simple cases.
+	// Math left in code still has the LaTeX delimiter $$$, replace it with $ because it looks ugly.
 	markdown = strings.ReplaceAll(markdown, "$$$", "$")
-	return markdown, imgURLs
+	return markdown, imgURLs, displayMath
+}
+
+// Size in pixels below which an image is taken to be an icon.
+const iconSizeLimit = 48
+
+// Returns whether an image is a small icon, like the emoji and flags that Codeforces inlines,
+// judging by its size and source.
+func isIconImage(img *goquery.Selection) bool {
+	if iconSrcRe.MatchString(img.AttrOr("src", "")) {
+		return true
+	}
+	for _, attr := range []string{"width", "height"} {
+		if size, err := strconv.Atoi(strings.TrimSuffix(img.AttrOr(attr, ""), "px")); err == nil &&
+			size < iconSizeLimit {
+			return true
+		}
+	}
+	for _, match := range styleSizeRe.FindAllStringSubmatch(img.AttrOr("style", ""), -1) {
+		if size, _ := strconv.Atoi(match[2]); size < iconSizeLimit {
+			return true
+		}
+	}
+	return false
 }
 
 func parseTime(selec *goquery.Selection) (t time.Time, err error) {
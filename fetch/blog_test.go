@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -12,9 +13,25 @@ import (
 	"github.com/go-test/deep"
 )
 
+// Revision pages are served for URLs like https://codeforces.com/topic/1/en<revision>, revision r
+// being from Feb r, 2021.
+const (
+	blogRevURLPrefix = "https://codeforces.com/topic/"
+	blogRevFmt       = `<div class="topic"><div class="info"><span class="format-humantime" title="Feb/%[1]v/2021 10:00"></span></div><div class="ttypography"><p>blog revision %[1]v</p></div></div>`
+)
+
+var blogRevURLRe = regexp.MustCompile(`\d+$`)
+
 func testParseBlog(t *testing.T, filename string, want *BlogInfo) {
+	fetchBlogPage := pageFetcherFor(filename, "testurl")
 	f := Fetcher{
-		FetchPage: pageFetcherFor(filename, "testurl"),
+		FetchPage: func(ctx context.Context, url string) (*goquery.Document, error) {
+			if !strings.HasPrefix(url, blogRevURLPrefix) {
+				return fetchBlogPage(ctx, url)
+			}
+			return goquery.NewDocumentFromReader(strings.NewReader(
+				fmt.Sprintf(blogRevFmt, blogRevURLRe.FindString(url))))
+		},
 		FetchAvatar: func(_ context.Context, handle string) (string, error) {
 			if handle != want.AuthorHandle {
 				t.Fatalf("got %v, want %v", handle, want.AuthorHandle)
@@ -22,13 +39,33 @@ func testParseBlog(t *testing.T, filename string, want *BlogInfo) {
 			return "fetchedavatarurl", nil
 		},
 	}
-	got, err := f.Blog(context.Background(), "testurl")
+	gotRevCnt, getter, err := f.Blog(context.Background(), "testurl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotRevCnt != want.RevisionCount {
+		t.Fatalf("got %v, want %v", gotRevCnt, want.RevisionCount)
+	}
+	got, err := getter(want.RevisionCount)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if diff := deep.Equal(got, want); diff != nil {
 		t.Fatal(diff)
 	}
+	for i := 1; i < want.RevisionCount; i++ {
+		got, err = getter(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantCopy := *want
+		wantCopy.Revision = i
+		wantCopy.Content = fmt.Sprintf("blog revision %v", i)
+		wantCopy.Images = nil
+		if diff := deep.Equal(got, &wantCopy); diff != nil {
+			t.Fatal(diff)
+		}
+	}
 }
 
 func TestParseBlog(t *testing.T) {
@@ -44,12 +81,15 @@ The problems contain extremely weak pretests. Time limits are too tight. I made
 Thanks, Mike.
 
 **UPD:** Thanks! It seems no bugs have been found.`,
-			CreationTime: time.Date(2020, 7, 24, 5, 37, 0, 0, time.UTC),
-			AuthorHandle: "MikeMirzayanov",
-			AuthorAvatar: "https://userpic.codeforces.com/11/avatar/7d0648e330a57263.jpg",
-			AuthorColor:  colorClsMap["user-admin"],
-			Rating:       130,
-			URL:          "testurl",
+			CreationTime:  time.Date(2020, 7, 24, 5, 37, 0, 0, time.UTC),
+			AuthorHandle:  "MikeMirzayanov",
+			AuthorAvatar:  "https://userpic.codeforces.com/11/avatar/7d0648e330a57263.jpg",
+			AuthorColor:   colorClsMap["user-admin"],
+			Rating:        130,
+			URL:           "testurl",
+			CommentCount:  23,
+			RevisionCount: 1,
+			Revision:      1,
 		}
 		testParseBlog(t, "blog_entry_80540.html", want)
 	})
@@ -70,13 +110,17 @@ In this lesson, we will learn how to build a simple segment tree (without mass m
 More about EDU section you can read in [this](https://codeforces.com/blog/entry/79530) post.
 
 Hope it will be helpful, enjoy!`,
-			Images:       []string{"https://codeforces.com/predownloaded/ad/f8/adf89a39c4a3c646e9b047bc1e24e894a01034d4.png"},
-			CreationTime: time.Date(2020, 7, 12, 10, 32, 0, 0, time.UTC),
-			AuthorHandle: "pashka",
-			AuthorAvatar: "fetchedavatarurl",
-			AuthorColor:  colorClsMap["user-red"],
-			Rating:       1309,
-			URL:          "testurl",
+			Images:        []string{"https://codeforces.com/predownloaded/ad/f8/adf89a39c4a3c646e9b047bc1e24e894a01034d4.png"},
+			CreationTime:  time.Date(2020, 7, 12, 10, 32, 0, 0, time.UTC),
+			AuthorHandle:  "pashka",
+			AuthorAvatar:  "fetchedavatarurl",
+			AuthorColor:   colorClsMap["user-red"],
+			Rating:        1309,
+			URL:           "testurl",
+			Tags:          []string{"edu", "segment tree"},
+			CommentCount:  25,
+			RevisionCount: 1,
+			Revision:      1,
 		}
 		testParseBlog(t, "blog_entry_80031.html", want)
 	})
@@ -95,13 +139,18 @@ Hope it will be helpful, enjoy!`,
 Отборочный тур пройдет онлайн с 27 февраля по 3 марта, старт виртуальный. Начать решать задачи в эти даты можно в любой момент, на решение дается 3 часа. По итогам отбора лучших участников мы пригласим в финал соревнования, который состоится во второй половине марта или начале апреля. Заключительный этап олимпиады также пройдет онлайн с применением прокторинга.
 
 [Подайте заявку на участие!](https://acm.kontur.ru/registration/getregistrationpage?competitionid=1197be17-7db3-4e6c-818e-636499cad171)`,
-			Images:       []string{"https://codeforces.com/predownloaded/5b/78/5b78a8862b972fa67b6fd9436b32a9a01f523128.png"},
-			CreationTime: time.Date(2021, 2, 2, 8, 55, 0, 0, time.UTC),
-			AuthorHandle: "xoposhiy",
-			AuthorAvatar: "fetchedavatarurl",
-			AuthorColor:  colorClsMap["user-black"],
-			Rating:       72,
-			URL:          "testurl",
+			Images:        []string{"https://codeforces.com/predownloaded/5b/78/5b78a8862b972fa67b6fd9436b32a9a01f523128.png"},
+			CreationTime:  time.Date(2021, 2, 2, 8, 55, 0, 0, time.UTC),
+			AuthorHandle:  "xoposhiy",
+			AuthorAvatar:  "fetchedavatarurl",
+			AuthorColor:   colorClsMap["user-black"],
+			Rating:        72,
+			URL:           "testurl",
+			Tags:          []string{"сп в урфу", "рсош", "олимпиада", "для школьников"},
+			CommentCount:  1,
+			RevisionCount: 11,
+			Revision:      11,
+			LastEditTime:  time.Date(2021, 2, 11, 7, 0, 0, 0, time.UTC),
 		}
 		testParseBlog(t, "blog_entry_87432.html", want)
 	})
@@ -109,6 +158,41 @@ Hope it will be helpful, enjoy!`,
 
 const commentRevFmt = `<div class="ttypography"><p>comment revision %v</p></div>`
 
+func TestParseBlogLastEditTime(t *testing.T) {
+	fetchBlogPage := pageFetcherFor("blog_entry_87432.html", "testurl")
+	fetchRevisionPage := pageFetcherFor(
+		"topic_87977_ru11.html", "https://codeforces.com/topic/87977/ru11")
+	f := Fetcher{
+		FetchPage: func(ctx context.Context, url string) (*goquery.Document, error) {
+			if url == "testurl" {
+				return fetchBlogPage(ctx, url)
+			}
+			return fetchRevisionPage(ctx, url)
+		},
+		FetchAvatar: func(context.Context, string) (string, error) { return "fetchedavatarurl", nil },
+	}
+	_, getter, err := f.Blog(context.Background(), "testurl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := getter(11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2021, 2, 11, 7, 0, 0, 0, time.UTC); !got.LastEditTime.Equal(want) {
+		t.Fatalf("got %v, want %v", got.LastEditTime, want)
+	}
+
+	// The blog is still parsed if the revision page cannot be fetched.
+	f.FetchPage = fetchBlogPage
+	if _, getter, err = f.Blog(context.Background(), "testurl"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = getter(11); !got.LastEditTime.IsZero() {
+		t.Fatalf("got %v, want zero", got.LastEditTime)
+	}
+}
+
 func testParseComment(t *testing.T, filename string, commentID string, want *CommentInfo) {
 	f := Fetcher{
 		FetchPageWithClient: pageFetcherWithClientFor(filename, "testurl"),
@@ -167,10 +251,83 @@ func TestParseComment(t *testing.T) {
 			Revision:      1,
 			Rating:        4,
 			URL:           "testurl",
+			ReplyCount:    1,
 		}
 		testParseComment(t, "blog_entry_80540.html", "667681", want)
 	})
 
+	t.Run("reply", func(t *testing.T) {
+		want := &CommentInfo{
+			Content:       "There is a bug in normal friends standings for a particular problem, after selecting friends only checkbox , we can't sort the standings other than based on solution size, please fix this soon. Example: [https://codeforces.com/contest/1380/status/A?friends=on](https://codeforces.com/contest/1380/status/A?friends=on) open this url, and try sorting according according to execution time or submission time, it redirects to /status route.\n\nThankss",
+			BlogTitle:     "EDU: Segment Tree, part 1",
+			CreationTime:  time.Date(2020, 7, 13, 16, 13, 0, 0, time.UTC),
+			AuthorHandle:  "abhishekvtangod",
+			AuthorColor:   colorClsMap["user-cyan"],
+			AuthorAvatar:  "https://userpic.codeforces.com/1212635/avatar/a4ef531992b813b7.jpg",
+			RevisionCount: 1,
+			Revision:      1,
+			Rating:        18,
+			URL:           "testurl",
+			Parents: []*CommentInfo{
+				{
+					Content:       "We will implement it soon.",
+					BlogTitle:     "EDU: Segment Tree, part 1",
+					CreationTime:  time.Date(2020, 7, 12, 12, 52, 0, 0, time.UTC),
+					AuthorHandle:  "MikeMirzayanov",
+					AuthorColor:   colorClsMap["user-admin"],
+					AuthorAvatar:  "https://userpic.codeforces.com/11/avatar/7d0648e330a57263.jpg",
+					RevisionCount: 1,
+					Revision:      1,
+					Rating:        57,
+					URL:           "testurl#comment-660494",
+				},
+				{
+					Content:       "Please add \"Friends standings\" option in the standings",
+					BlogTitle:     "EDU: Segment Tree, part 1",
+					CreationTime:  time.Date(2020, 7, 12, 12, 25, 0, 0, time.UTC),
+					AuthorHandle:  "--Someone--",
+					AuthorColor:   colorClsMap["user-orange"],
+					AuthorAvatar:  "https://userpic.codeforces.com/556758/avatar/4ec3848fed207db4.jpg",
+					RevisionCount: 1,
+					Revision:      1,
+					Rating:        34,
+					URL:           "testurl#comment-660475",
+				},
+			},
+		}
+		testParseComment(t, "blog_entry_80031.html", "661576", want)
+	})
+
+	t.Run("deletedParent", func(t *testing.T) {
+		want := &CommentInfo{
+			Content:       "There is a bug in normal friends standings for a particular problem, after selecting friends only checkbox , we can't sort the standings other than based on solution size, please fix this soon. Example: [https://codeforces.com/contest/1380/status/A?friends=on](https://codeforces.com/contest/1380/status/A?friends=on) open this url, and try sorting according according to execution time or submission time, it redirects to /status route.\n\nThankss",
+			BlogTitle:     "EDU: Segment Tree, part 1",
+			CreationTime:  time.Date(2020, 7, 13, 16, 13, 0, 0, time.UTC),
+			AuthorHandle:  "abhishekvtangod",
+			AuthorColor:   colorClsMap["user-cyan"],
+			AuthorAvatar:  "https://userpic.codeforces.com/1212635/avatar/a4ef531992b813b7.jpg",
+			RevisionCount: 1,
+			Revision:      1,
+			Rating:        18,
+			URL:           "testurl",
+			Parents: []*CommentInfo{
+				{
+					Content:       "We will implement it soon.",
+					BlogTitle:     "EDU: Segment Tree, part 1",
+					CreationTime:  time.Date(2020, 7, 12, 12, 52, 0, 0, time.UTC),
+					AuthorHandle:  "MikeMirzayanov",
+					AuthorColor:   colorClsMap["user-admin"],
+					AuthorAvatar:  "https://userpic.codeforces.com/11/avatar/7d0648e330a57263.jpg",
+					RevisionCount: 1,
+					Revision:      1,
+					Rating:        57,
+					URL:           "testurl#comment-660494",
+				},
+			},
+		}
+		testParseComment(t, "blog_entry_80031_deleted_parent.html", "661576", want)
+	})
+
 	t.Run("withRevisions", func(t *testing.T) {
 		want := &CommentInfo{
 			Content:       "I accidentally submitted the code for merging the intervals using `+` operator instead of `max()` and I am getting WA in C But the same gives AC on D in step-2 should this be happening?",
@@ -188,4 +345,62 @@ func TestParseComment(t *testing.T) {
 	})
 }
 
+func TestParseBlogContest(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		html string
+		want *BlogContest
+	}{
+		{
+			"none",
+			`<div class="topic"><div class="meta"><a href="/blog/entry/1#comments">1</a></div></div>`,
+			nil,
+		},
+		{
+			"under blog",
+			`<div class="topic"><div class="meta"><a href="/contest/1390/">Codeforces Round #1</a></div></div>`,
+			&BlogContest{Name: "Codeforces Round #1", URL: "https://codeforces.com/contest/1390"},
+		},
+		{
+			"sidebar",
+			`<div class="topic"></div><div id="sidebar">
+			<div class="caption"><a href="/contests/1462">Upcoming</a></div>
+			<div class="caption"><a href="/gym/102000">Training</a></div></div>`,
+			&BlogContest{Name: "Training", URL: "https://codeforces.com/gym/102000"},
+		},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := parseBlogContest(doc, doc.FindMatcher(blogSelec))
+		if diff := deep.Equal(got, test.want); diff != nil {
+			t.Errorf("%v: %v", test.name, diff)
+		}
+	}
+}
+
 // TODO: Add tests for various comment contents (formattings, spoilers, images, etc)
+
+func TestIsIconImage(t *testing.T) {
+	for _, test := range []struct {
+		html string
+		want bool
+	}{
+		{`<img src="/predownloaded/ad/f8/adf8.png" style="max-width: 100.0%;max-height: 100.0%;">`, false},
+		{`<img src="/predownloaded/ad/f8/adf8.png" width="640" height="480">`, false},
+		{`<img src="/predownloaded/ad/f8/adf8.png" width="20" height="20">`, true},
+		{`<img src="/predownloaded/ad/f8/adf8.png" style="width: 16px; height: 16px">`, true},
+		{`<img src="//codeforces.org/s/0/images/flags/24/gb.png">`, true},
+		{`<img src="/images/smiles/smile.png">`, true},
+		{`<img src="https://cdn.example.com/emoji/1f600.png">`, true},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isIconImage(doc.Find("img")); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.html, got, test.want)
+		}
+	}
+}
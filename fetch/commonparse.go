@@ -40,3 +40,11 @@ func userColor(userSelec *goquery.Selection) (color int) {
 	}
 	return
 }
+
+// Returns the rank of the user from the title of the handle link, like "Pupil" for
+// "Pupil handle" and "Unrated" for "Unrated, handle".
+func userRank(userSelec *goquery.Selection) string {
+	title := userSelec.AttrOr("title", "")
+	title = strings.TrimSuffix(title, userSelec.Text())
+	return strings.TrimRight(title, ", ")
+}
@@ -0,0 +1,245 @@
+package fetch
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Markdown conversion rules for content, on top of the commonmark rules. These are suited to
+// Discord, which supports code blocks with languages and one level of quotes, but neither tables
+// nor nested lists.
+var contentRules = []md.Rule{
+	{
+		Filter:      []string{"pre"},
+		Replacement: codeBlockReplacement,
+	},
+	{
+		Filter:      []string{"table"},
+		Replacement: tableReplacement,
+	},
+	{
+		Filter:      []string{"blockquote"},
+		Replacement: quoteReplacement,
+	},
+	{
+		Filter:      []string{"ul", "ol"},
+		Replacement: listReplacement,
+	},
+	{
+		Filter:      []string{"li"},
+		Replacement: listItemReplacement,
+	},
+}
+
+// Patterns that identify the language of code, tried in order.
+var codeLanguagePatterns = []struct {
+	lang string
+	re   *regexp.Regexp
+}{
+	{"cpp", regexp.MustCompile(`#include\s*[<"]|using\s+namespace\s+std|\bint\s+main\s*\(`)},
+	{"java", regexp.MustCompile(`\bpublic\s+(static\s+)?(class|void)\b|\bimport\s+java\.|System\.out\.`)},
+	{"kotlin", regexp.MustCompile(`\bfun\s+main\s*\(`)},
+	{"rust", regexp.MustCompile(`\bfn\s+main\s*\(|\blet\s+mut\s`)},
+	{"go", regexp.MustCompile(`(?m)^package\s+\w+\s*$`)},
+	{"python", regexp.MustCompile(
+		`(?m)^\s*def\s+\w+\(.*\)\s*:\s*$|^\s*for\s+\w+\s+in\s+.*:\s*$|^(from\s+\w+\s+)?import\s+\w+\s*$`)},
+}
+
+// Language names in classes, like lang-c++, that Discord does not know.
+var codeLanguageAliases = map[string]string{
+	"c++": "cpp", "cc": "cpp", "py": "python", "py3": "python", "kt": "kotlin", "rs": "rust",
+	"golang": "go", "c#": "cs", "csharp": "cs",
+}
+
+var bulletMarkers = []string{"•", "◦", "▪"}
+
+func codeBlockReplacement(_ string, selec *goquery.Selection, opt *md.Options) *string {
+	code := selec.Find("code")
+	if code.Length() == 0 {
+		code = selec
+	}
+	code.Find("br").ReplaceWithHtml("\n")
+	text := strings.Trim(code.Text(), "\n")
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	if text == "" {
+		return md.String("")
+	}
+	fenceChar, _ := utf8.DecodeRuneInString(opt.Fence)
+	fence := md.CalculateCodeFence(fenceChar, text)
+	lang := codeBlockLanguage(selec.AddSelection(code), text)
+	return md.String("\n\n" + fence + lang + "\n" + text + "\n" + fence + "\n\n")
+}
+
+// Returns the language of a code block from classes like "prettyprint lang-cpp", or failing that,
+// from the code. Returns "" if unknown.
+func codeBlockLanguage(selec *goquery.Selection, code string) string {
+	var lang string
+	selec.EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, cls := range strings.Fields(s.AttrOr("class", "")) {
+			for _, prefix := range []string{"lang-", "language-"} {
+				if strings.HasPrefix(cls, prefix) {
+					lang = strings.ToLower(strings.TrimPrefix(cls, prefix))
+					return false
+				}
+			}
+		}
+		return true
+	})
+	if alias, ok := codeLanguageAliases[lang]; ok {
+		return alias
+	}
+	if lang != "" {
+		return lang
+	}
+	return DetectCodeLanguage(code)
+}
+
+// DetectCodeLanguage returns the language of code as a code block language like "cpp", from
+// patterns common in competitive programming code. Returns "" if unknown.
+func DetectCodeLanguage(code string) string {
+	for _, pattern := range codeLanguagePatterns {
+		if pattern.re.MatchString(code) {
+			return pattern.lang
+		}
+	}
+	return ""
+}
+
+// Renders a table as a code block with aligned columns, since Discord does not support tables.
+func tableReplacement(_ string, selec *goquery.Selection, opt *md.Options) *string {
+	var rows [][]string
+	hasHeader := false
+	selec.Find("tr").Each(func(i int, tr *goquery.Selection) {
+		if !tr.ParentsFiltered("table").First().IsSelection(selec) {
+			return // Row of a nested table
+		}
+		var row []string
+		allHeaders := true
+		tr.ChildrenFiltered("td, th").Each(func(_ int, cell *goquery.Selection) {
+			row = append(row, strings.Join(strings.Fields(cell.Text()), " "))
+			allHeaders = allHeaders && goquery.NodeName(cell) == "th"
+		})
+		if len(row) == 0 {
+			return
+		}
+		if len(rows) == 0 {
+			hasHeader = allHeaders || tr.ParentsFiltered("thead").Length() > 0
+		}
+		rows = append(rows, row)
+	})
+	if len(rows) == 0 {
+		return md.String("")
+	}
+
+	var widths []int
+	for _, row := range rows {
+		for j, cell := range row {
+			if j == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+	var lines []string
+	for i, row := range rows {
+		cells := make([]string, len(widths))
+		for j := range widths {
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+			cells[j] = cell + strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell))
+		}
+		lines = append(lines, strings.TrimRightFunc(strings.Join(cells, " | "), unicode.IsSpace))
+		if i == 0 && hasHeader {
+			rules := make([]string, len(widths))
+			for j, w := range widths {
+				rules[j] = strings.Repeat("-", w)
+			}
+			lines = append(lines, strings.Join(rules, "-+-"))
+		}
+	}
+	text := strings.Join(lines, "\n")
+	fenceChar, _ := utf8.DecodeRuneInString(opt.Fence)
+	fence := md.CalculateCodeFence(fenceChar, text)
+	return md.String("\n\n" + fence + "\n" + text + "\n" + fence + "\n\n")
+}
+
+// Renders a quote with one level of "> ", since Discord does not support nested quotes. Blank
+// lines are dropped because they would end the quote.
+func quoteReplacement(content string, selec *goquery.Selection, _ *md.Options) *string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return md.String("")
+	}
+	if selec.ParentsFiltered("blockquote").Length() > 0 {
+		// Quoted along with the outer quote.
+		return md.String("\n\n" + content + "\n\n")
+	}
+	lines := strings.Split(dropBlankLines(content), "\n")
+	for i := range lines {
+		lines[i] = "> " + lines[i]
+	}
+	return md.String("\n\n" + strings.Join(lines, "\n") + "\n\n")
+}
+
+func listReplacement(content string, selec *goquery.Selection, _ *md.Options) *string {
+	content = strings.Trim(content, "\n")
+	if selec.Parent().Is("li") {
+		// Nested lists start on a new line of the item, which indents them.
+		return md.String("\n" + content)
+	}
+	return md.String("\n\n" + content + "\n\n")
+}
+
+// Renders a list item with a bullet or number, and the item content, including nested lists,
+// indented under it.
+func listItemReplacement(content string, selec *goquery.Selection, _ *md.Options) *string {
+	content = dropBlankLines(strings.TrimSpace(content))
+	if content == "" {
+		return md.String("")
+	}
+	var marker string
+	if list := selec.Parent(); list.Is("ol") {
+		start, err := strconv.Atoi(list.AttrOr("start", "1"))
+		if err != nil {
+			start = 1
+		}
+		marker = strconv.Itoa(start+selec.PrevAllFiltered("li").Length()) + "."
+	} else {
+		depth := selec.ParentsFiltered("ul").Length() - 1
+		marker = bulletMarkers[depth%len(bulletMarkers)]
+	}
+	prefix := marker + " "
+	indent := strings.Repeat(" ", utf8.RuneCountInString(prefix))
+	lines := strings.Split(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return md.String(prefix + strings.Join(lines, "\n") + "\n")
+}
+
+// Removes blank lines, except in code blocks.
+func dropBlankLines(s string) string {
+	var lines []string
+	inCode := false
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if inCode || strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,51 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestContentFormatting(t *testing.T) {
+	doc, err := loadHtmlTestFile("blog_content_formatting.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/blog_content_formatting.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, _ := getContentAsMarkdown(doc.FindMatcher(typographySelec))
+	if got != strings.TrimSuffix(string(want), "\n") {
+		t.Fatalf("got\n%v\n\nwant\n%v", got, string(want))
+	}
+}
+
+func TestCodeBlockLanguage(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		html string
+		want string
+	}{
+		{"class", `<pre class="prettyprint lang-java">x</pre>`, "java"},
+		{"alias", `<pre class="prettyprint lang-c++">x</pre>`, "cpp"},
+		{"code class", `<pre><code class="language-rust">x</code></pre>`, "rust"},
+		{"cpp", "<pre>#include &lt;cstdio&gt;\nint main() {}</pre>", "cpp"},
+		{"java", "<pre>public class Main {\n}</pre>", "java"},
+		{"kotlin", "<pre>fun main() {\n}</pre>", "kotlin"},
+		{"go", "<pre>package main\n\nfunc main() {}</pre>", "go"},
+		{"python", "<pre>for i in range(n):\n    print(i)</pre>", "python"},
+		{"unknown", "<pre>5\n1 2 3 4 5</pre>", ""},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pre := doc.Find("pre")
+		if got := codeBlockLanguage(pre.AddSelection(pre.Find("code")), pre.Text()); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package fetch
 import (
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -13,7 +14,7 @@ var (
 	commentFragmentRe = regexp.MustCompile(`comment-(\d+)`)
 	problemURLRe      = regexp.MustCompile(`https?://codeforces.com/(?:(?:contest|gym)/\d+/problem|problemset/problem/\d+|problemsets/acmsguru/problem/\d+)/\w+` + queryAndFragment)
 	submissionURLRe   = regexp.MustCompile(`https?://codeforces.com/(?:(?:contest|gym)/\d+/submission|problemset/submission/\d+)/\d+` + queryAndFragment)
-	lineNumFragmentRe = regexp.MustCompile(`L(\d+)(?:-L(\d+))?`)
+	lineNumFragmentRe = regexp.MustCompile(`L(\d+)(?:-L(\d+))?|C(\d+)`)
 	profileURLRe      = regexp.MustCompile(`https?://codeforces.com/profile/[\w-.]*[\w-]` + queryAndFragment)
 )
 
@@ -78,22 +79,42 @@ func ParseSubmissionURLs(s string) []*SubmissionURLMatch {
 		match := SubmissionURLMatch{
 			URL: urlMatch,
 		}
-		lineNumsMatch := lineNumFragmentRe.FindStringSubmatch(parsedURL.Fragment)
-		if len(lineNumsMatch) > 0 {
-			if match.LineBegin, err = strconv.Atoi(lineNumsMatch[1]); err == nil {
-				if match.LineEnd, err = strconv.Atoi(lineNumsMatch[2]); err != nil {
-					match.LineEnd = match.LineBegin
-				}
-			}
-			if match.LineBegin > match.LineEnd {
-				match.LineBegin, match.LineEnd = match.LineEnd, match.LineBegin
-			}
-		}
+		match.Lines, match.ContextLines = parseLineNumFragment(parsedURL.Fragment)
 		matches = append(matches, &match)
 	}
 	return matches
 }
 
+// Parses line ranges and context lines from a fragment like L5-L8,L30-L42,C3. Ranges are sorted by
+// their first line.
+func parseLineNumFragment(fragment string) (lines []LineRange, contextLines int) {
+	for _, m := range lineNumFragmentRe.FindAllStringSubmatch(fragment, -1) {
+		if m[3] != "" {
+			contextLines, _ = strconv.Atoi(m[3])
+			continue
+		}
+		begin, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		end, err := strconv.Atoi(m[2])
+		if err != nil {
+			end = begin
+		}
+		if begin > end {
+			begin, end = end, begin
+		}
+		lines = append(lines, LineRange{Begin: begin, End: end})
+	}
+	if len(lines) == 0 {
+		return nil, 0
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].Begin < lines[j].Begin
+	})
+	return
+}
+
 // ParseProfileURLs parses Codeforces profile URLS from the given string.
 func ParseProfileURLs(s string) []*ProfileURLMatch {
 	s = removeSpoilers(s)
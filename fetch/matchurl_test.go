@@ -144,6 +144,28 @@ func TestParseSubmissionURLs(t *testing.T) {
 				{URL: "https://codeforces.com/contest/123/submission/123456?locale=ru#key=value"},
 			},
 		},
+		{"singleWithLines", "https://codeforces.com/contest/123/submission/123456#L20-L10",
+			[]*SubmissionURLMatch{
+				{
+					URL:   "https://codeforces.com/contest/123/submission/123456#L20-L10",
+					Lines: []LineRange{{Begin: 10, End: 20}},
+				},
+			},
+		},
+		{"singleWithRangesAndContext", "https://codeforces.com/contest/123/submission/123456#L30-L42,L5,C3",
+			[]*SubmissionURLMatch{
+				{
+					URL:          "https://codeforces.com/contest/123/submission/123456#L30-L42,L5,C3",
+					Lines:        []LineRange{{Begin: 5, End: 5}, {Begin: 30, End: 42}},
+					ContextLines: 3,
+				},
+			},
+		},
+		{"singleWithOnlyContext", "https://codeforces.com/contest/123/submission/123456#C3",
+			[]*SubmissionURLMatch{
+				{URL: "https://codeforces.com/contest/123/submission/123456#C3"},
+			},
+		},
 		{"multiple",
 			"See https://codeforces.com/contest/123/submission/123456 and <https://codeforces.com/gym/123456/submission/54321> " +
 				"and ||https://codeforces.com/gym/123456/submission/54321||. ",
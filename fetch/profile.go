@@ -16,6 +16,10 @@ var (
 	rankSelec    = cascadia.MustCompile(".user-rank")
 	photoSelec   = cascadia.MustCompile(".title-photo img")
 	infoLiSelec  = cascadia.MustCompile("li")
+	maxRankSelec = cascadia.MustCompile(".smaller span")
+	orgSelec     = cascadia.MustCompile(`a[href^="/ratings/organization/"]`)
+	citySelec    = cascadia.MustCompile(`a[href^="/ratings/country/"][href*="/city/"]`)
+	countrySelec = cascadia.MustCompile(`a[href^="/ratings/country/"]:not([href*="/city/"])`)
 	numberRe     = regexp.MustCompile("-?[0-9]+")
 )
 
@@ -46,22 +50,40 @@ func (f *Fetcher) Profile(ctx context.Context, url string) (*ProfileInfo, error)
 	infoDiv := doc.FindMatcher(infoDivSelec)
 	p.Handle, p.Color = parseHandleAndColor(infoDiv)
 	p.Rank = strings.TrimSpace(doc.FindMatcher(rankSelec).Text())
+	p.Organization = strings.TrimSpace(infoDiv.FindMatcher(orgSelec).First().Text())
+	p.City = strings.TrimSpace(infoDiv.FindMatcher(citySelec).First().Text())
+	p.Country = strings.TrimSpace(infoDiv.FindMatcher(countrySelec).First().Text())
 	lis := infoDiv.FindMatcher(infoLiSelec)
 	lis.EachWithBreak(func(_ int, s *goquery.Selection) bool {
-		text := s.Text()
-		if !strings.Contains(text, "Contest rating") {
-			return true
-		}
-		// Format is "Contest rating: <value> (max. <rank>, <value>)"
-		text = strings.Join(strings.Fields(text), " ")
-		numbers := numberRe.FindAllString(text, -1)
-		if len(numbers) == 2 {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		switch {
+		case strings.HasPrefix(text, "Contest rating:"):
+			// Format is "Contest rating: <value> (max. <rank>, <value>)"
+			numbers := numberRe.FindAllString(text, -1)
+			if len(numbers) != 2 {
+				err = fmt.Errorf("Unexpected format of rating line: %v", text)
+				return false
+			}
 			p.Rating, _ = strconv.Atoi(numbers[0])
 			p.MaxRating, _ = strconv.Atoi(numbers[1])
-		} else {
-			err = fmt.Errorf("Unexpected format of rating line: %v", text)
+			maxRank := strings.TrimSpace(s.FindMatcher(maxRankSelec).First().Text())
+			p.MaxRank = strings.Title(strings.TrimSuffix(maxRank, ","))
+		case strings.HasPrefix(text, "Contribution:"):
+			p.Contribution, _ = strconv.Atoi(numberRe.FindString(text))
+		case strings.HasPrefix(text, "Friend of:"):
+			// Format is "Friend of: <value> users", with thousands separated by commas
+			p.FriendOf, _ = strconv.Atoi(numberRe.FindString(strings.ReplaceAll(text, ",", "")))
+		case strings.HasPrefix(text, "Registered:"):
+			if t, err := parseTime(s); err == nil {
+				p.Registered = t
+			}
+		case strings.HasPrefix(text, "Last visit:"):
+			// Users online now have no time.
+			if t, err := parseTime(s); err == nil {
+				p.LastVisit = t
+			}
 		}
-		return false
+		return err == nil
 	})
 	if err != nil {
 		return nil, err
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-test/deep"
@@ -34,6 +35,11 @@ func TestParseProfile(t *testing.T) {
 			Color:     colorClsMap["user-violet"],
 			Avatar:    "https://userpic.codeforces.com/408096/title/2ebfef1265c2f1e6.jpg",
 			URL:       "testurl",
+
+			MaxRank:    "International Master",
+			FriendOf:   1450,
+			Registered: time.Date(2016, 4, 12, 22, 56, 0, 0, time.UTC),
+			LastVisit:  time.Date(2021, 2, 14, 3, 14, 0, 0, time.UTC),
 		}
 		testParseProfile(t, "profile_rainboy.html", want)
 	})
@@ -46,6 +52,12 @@ func TestParseProfile(t *testing.T) {
 			Color:     colorClsMap["user-gray"],
 			Avatar:    "https://userpic.codeforces.com/no-title.jpg",
 			URL:       "testurl",
+
+			MaxRank:      "Pupil",
+			Contribution: -5,
+			FriendOf:     63,
+			Registered:   time.Date(2018, 3, 24, 8, 18, 0, 0, time.UTC),
+			LastVisit:    time.Date(2020, 11, 13, 2, 22, 0, 0, time.UTC),
 		}
 		testParseProfile(t, "profile___123456__.html", want)
 	})
@@ -58,6 +70,13 @@ func TestParseProfile(t *testing.T) {
 			Color:     colorClsMap["user-black"],
 			Avatar:    "https://userpic.codeforces.com/no-title.jpg",
 			URL:       "testurl",
+
+			Contribution: -13,
+			FriendOf:     51,
+			City:         "Beijing",
+			Country:      "China",
+			Registered:   time.Date(2017, 7, 24, 14, 17, 0, 0, time.UTC),
+			LastVisit:    time.Date(2021, 1, 30, 16, 32, 0, 0, time.UTC),
 		}
 		testParseProfile(t, "profile_LanceTheDragonTrainer.html", want)
 	})
@@ -70,6 +89,15 @@ func TestParseProfile(t *testing.T) {
 			Color:     colorClsMap["user-admin"],
 			Avatar:    "https://userpic.codeforces.com/226065/title/c40b38db239bbdab.jpg",
 			URL:       "testurl",
+
+			MaxRank:      "Expert",
+			Contribution: 179,
+			FriendOf:     187,
+			Organization: "Poisk",
+			City:         "Stavropol",
+			Country:      "Russia",
+			Registered:   time.Date(2014, 8, 6, 9, 19, 0, 0, time.UTC),
+			LastVisit:    time.Date(2021, 2, 18, 14, 23, 0, 0, time.UTC),
 		}
 		testParseProfile(t, "profile_geranazavr555.html", want)
 	})
@@ -82,11 +110,41 @@ func TestParseProfile(t *testing.T) {
 			Color:     colorClsMap["user-admin"],
 			Avatar:    "https://userpic.codeforces.com/11/title/c7fb4051127c29e4.jpg",
 			URL:       "testurl",
+
+			Contribution: 250,
+			FriendOf:     5961,
+			Organization: "ITMO University",
+			City:         "Saratov",
+			Country:      "Russia",
+			Registered:   time.Date(2010, 1, 28, 21, 36, 0, 0, time.UTC),
+			LastVisit:    time.Date(2021, 2, 14, 0, 57, 0, 0, time.UTC),
 		}
 		testParseProfile(t, "profile_MikeMirzayanov.html", want)
 	})
 }
 
+func TestParseProfileOnlineNow(t *testing.T) {
+	doc, err := loadHtmlTestFile("profile_rainboy.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.FindMatcher(infoLiSelec).Each(func(_ int, li *goquery.Selection) {
+		if strings.Contains(li.Text(), "Last visit:") {
+			li.FindMatcher(timeSelec).ReplaceWithHtml("online now")
+		}
+	})
+	f := Fetcher{
+		FetchPage: func(context.Context, string) (*goquery.Document, error) { return doc, nil },
+	}
+	got, err := f.Profile(context.Background(), "testurl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.LastVisit.IsZero() || got.Registered.IsZero() || got.Handle != "rainboy" {
+		t.Fatalf("got %+v, want the profile without the last visit", got)
+	}
+}
+
 func TestParseProfileLocaleParamStripped(t *testing.T) {
 	expected := errors.New("expected")
 	f := Fetcher{
@@ -2,6 +2,8 @@ package fetch
 
 import (
 	"context"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,12 +12,18 @@ import (
 )
 
 var (
-	infoRowSelec  = cascadia.MustCompile(".datatable tr") // Pick second
-	infoCellSelec = cascadia.MustCompile("td")
-	ghostSelec    = cascadia.MustCompile(`span[title="Ghost participant"]`)
-	teamNameSelec = cascadia.MustCompile(`a[href^="/team"]`)
-	problemSelec  = cascadia.MustCompile("a")
-	sourceSelec   = cascadia.MustCompile("#program-source-text")
+	infoRowSelec    = cascadia.MustCompile(".datatable tr") // Pick second
+	infoCellSelec   = cascadia.MustCompile("td")
+	ghostSelec      = cascadia.MustCompile(`span[title="Ghost participant"]`)
+	teamSelec       = cascadia.MustCompile(`a[href^="/team"]`)
+	problemSelec    = cascadia.MustCompile("a")
+	sourceSelec     = cascadia.MustCompile("#program-source-text")
+	judgedTestSelec = cascadia.MustCompile(".verdict-format-judged")
+	verdictTestRe   = regexp.MustCompile(`^(.*?)\s+on\s+\S+\s+\d+$`)
+	problemPathRe   = regexp.MustCompile(`^/(?:contest|gym)/(\d+)/problem/`)
+	timeCellRe      = regexp.MustCompile(`^(\d+)\s*ms$`)
+	memoryCellRe    = regexp.MustCompile(`^(\d+)\s*KB$`)
+	teamPathRe      = regexp.MustCompile(`^/team/(\d+)`)
 )
 
 // Submission fetches submission information using the DefaultFetcher.
@@ -40,6 +48,7 @@ func (f *Fetcher) Submission(ctx context.Context, url string) (*SubmissionInfo,
 	// Rows are
 	// # | Author | Problem | Lang | Verdict | Time | Memory | Sent | Judged | <Compare>
 	// Sometimes, as in https://codeforces.com/contest/1386, time and memory are missing (?!)
+	// Columns are picked by position from both ends.
 
 	var s SubmissionInfo
 	infoRow := doc.FindMatcher(infoRowSelec).Eq(1).FindMatcher(infoCellSelec)
@@ -50,26 +59,67 @@ func (f *Fetcher) Submission(ctx context.Context, url string) (*SubmissionInfo,
 	authorCell := infoRow.Eq(1)
 	if s.AuthorGhost = parseGhost(authorCell); s.AuthorGhost == "" {
 		authors := parseAuthors(authorCell)
-		if teamName := parseTeamName(authorCell); teamName != "" {
-			s.AuthorTeam = &SubmissionInfoTeam{
-				Name:    teamName,
-				Authors: authors,
-			}
+		if s.AuthorTeam = parseTeam(authorCell); s.AuthorTeam != nil {
+			s.AuthorTeam.Authors = authors
 		} else {
 			s.Author = authors[0]
 		}
 	}
-	s.Problem = infoRow.Eq(2).FindMatcher(problemSelec).Text()
+	problemA := infoRow.Eq(2).FindMatcher(problemSelec)
+	s.Problem = problemA.Text()
+	if href, ok := problemA.Attr("href"); ok {
+		s.ProblemURL = withCodeforcesHost(href)
+		if match := problemPathRe.FindStringSubmatch(href); match != nil {
+			s.ContestID = match[1]
+		}
+	}
 	s.Language = strings.TrimSpace(infoRow.Eq(3).Text())
-	s.Verdict = strings.TrimSpace(infoRow.Eq(4).Text())
+	verdictCell := infoRow.Eq(4)
+	s.Verdict = strings.TrimSpace(verdictCell.Text())
+	s.VerdictKind, s.VerdictTest = parseVerdict(verdictCell)
+	if infoRow.Length() >= 10 {
+		s.Time, s.Memory = parseTimeAndMemory(infoRow.Eq(5).Text(), infoRow.Eq(6).Text())
+	}
 	if s.SentTime, err = parseSubmissionTime(infoRow.Eq(infoRow.Length() - 3).Text()); err != nil {
 		return nil, err
 	}
+	// Blank while the submission is in queue or being judged.
+	if t, err := parseSubmissionTime(infoRow.Eq(infoRow.Length() - 2).Text()); err == nil {
+		s.JudgedTime = t
+	}
 	s.Content = doc.FindMatcher(sourceSelec).Text()
 	s.URL = url
 	return &s, nil
 }
 
+// Splits the verdict into the verdict without the test and the test number, 0 if the verdict is not
+// on a test. Pretests are numbered like tests.
+func parseVerdict(verdictCell *goquery.Selection) (kind string, test int) {
+	verdict := strings.Join(strings.Fields(verdictCell.Text()), " ")
+	testSpan := verdictCell.FindMatcher(judgedTestSelec)
+	match := verdictTestRe.FindStringSubmatch(verdict)
+	if testSpan.Length() == 0 || match == nil {
+		return verdict, 0
+	}
+	test, err := strconv.Atoi(strings.TrimSpace(testSpan.Text()))
+	if err != nil {
+		return verdict, 0
+	}
+	return match[1], test
+}
+
+// Parses cells like "93 ms" and "4 KB". Unexpected values are taken as not shown.
+func parseTimeAndMemory(timeText, memoryText string) (t time.Duration, memory int) {
+	if match := timeCellRe.FindStringSubmatch(strings.TrimSpace(timeText)); match != nil {
+		ms, _ := strconv.Atoi(match[1])
+		t = time.Duration(ms) * time.Millisecond
+	}
+	if match := memoryCellRe.FindStringSubmatch(strings.TrimSpace(memoryText)); match != nil {
+		memory, _ = strconv.Atoi(match[1])
+	}
+	return
+}
+
 func parseGhost(authorCell *goquery.Selection) string {
 	if s := authorCell.FindMatcher(ghostSelec); s.Length() != 0 {
 		return s.Text()
@@ -77,20 +127,33 @@ func parseGhost(authorCell *goquery.Selection) string {
 	return ""
 }
 
-func parseTeamName(authorCell *goquery.Selection) string {
-	if s := authorCell.FindMatcher(teamNameSelec); s.Length() != 0 {
-		return s.Text()
+// Returns the team without authors, or nil if the author is not a team.
+func parseTeam(authorCell *goquery.Selection) *SubmissionInfoTeam {
+	s := authorCell.FindMatcher(teamSelec).First()
+	if s.Length() == 0 {
+		return nil
 	}
-	return ""
+	team := &SubmissionInfoTeam{Name: s.Text()}
+	href := s.AttrOr("href", "")
+	if match := teamPathRe.FindStringSubmatch(href); match != nil {
+		team.ID = match[1]
+		team.URL = withCodeforcesHost(href)
+	}
+	return team
 }
 
 func parseAuthors(authorCell *goquery.Selection) []*SubmissionInfoAuthor {
 	var authors []*SubmissionInfoAuthor
 	authorCell.FindMatcher(handleSelec).Each(func(_ int, s *goquery.Selection) {
-		authors = append(authors, &SubmissionInfoAuthor{
+		author := &SubmissionInfoAuthor{
 			Handle: s.Text(),
 			Color:  userColor(s),
-		})
+			Rank:   userRank(s),
+		}
+		if href, ok := s.Attr("href"); ok {
+			author.URL = withCodeforcesHost(href)
+		}
+		authors = append(authors, author)
 	})
 	return authors
 }
@@ -32,12 +32,21 @@ func TestParseSubmission(t *testing.T) {
 			Author: &SubmissionInfoAuthor{
 				Handle: "AM.EM.U4EAC19012",
 				Color:  colorClsMap["user-black"],
+				Rank:   "Unrated",
+				URL:    "https://codeforces.com/profile/AM.EM.U4EAC19012",
 			},
 			Problem:         "1267B",
+			ProblemURL:      "https://codeforces.com/contest/1267/problem/B",
+			ContestID:       "1267",
 			Language:        "Python 3",
 			Verdict:         "Wrong answer on test 1",
+			VerdictKind:     "Wrong answer",
+			VerdictTest:     1,
 			ParticipantType: "Practice",
 			SentTime:        time.Date(2019, 12, 12, 13, 23, 43, 0, time.UTC),
+			JudgedTime:      time.Date(2019, 12, 12, 13, 23, 43, 0, time.UTC),
+			Time:            93 * time.Millisecond,
+			Memory:          4,
 			URL:             "testurl",
 			Content:         "x=input()\n",
 		}
@@ -49,10 +58,14 @@ func TestParseSubmission(t *testing.T) {
 			ID:              "66173991",
 			AuthorGhost:     "SPb ITMO: Reduce (Korobkov, Ovechkin, Poduremennykh)",
 			Problem:         "1267A",
+			ProblemURL:      "https://codeforces.com/contest/1267/problem/A",
+			ContestID:       "1267",
 			Language:        "Unknown",
 			Verdict:         "Accepted",
+			VerdictKind:     "Accepted",
 			ParticipantType: "Virtual",
 			SentTime:        time.Date(2019, 12, 02, 11, 25, 44, 0, time.UTC),
+			JudgedTime:      time.Date(2019, 12, 02, 11, 25, 44, 0, time.UTC),
 			URL:             "testurl",
 		}
 		testParseSubmission(t, "contest_1267_submission_66173991.html", want)
@@ -62,18 +75,41 @@ func TestParseSubmission(t *testing.T) {
 		want := &SubmissionInfo{
 			ID: "66109629",
 			AuthorTeam: &SubmissionInfoTeam{
+				ID:   "65653",
 				Name: "RednBlack Tree Team",
+				URL:  "https://codeforces.com/team/65653",
 				Authors: []*SubmissionInfoAuthor{
-					{Handle: "IZOBRETATEL777", Color: colorClsMap["user-green"]},
-					{Handle: "emilprogrammist", Color: colorClsMap["user-green"]},
-					{Handle: "Sadykhzadeh", Color: colorClsMap["user-cyan"]},
+					{
+						Handle: "IZOBRETATEL777",
+						Color:  colorClsMap["user-green"],
+						Rank:   "Pupil",
+						URL:    "https://codeforces.com/profile/IZOBRETATEL777",
+					},
+					{
+						Handle: "emilprogrammist",
+						Color:  colorClsMap["user-green"],
+						Rank:   "Pupil",
+						URL:    "https://codeforces.com/profile/emilprogrammist",
+					},
+					{
+						Handle: "Sadykhzadeh",
+						Color:  colorClsMap["user-cyan"],
+						Rank:   "Specialist",
+						URL:    "https://codeforces.com/profile/Sadykhzadeh",
+					},
 				},
 			},
 			Problem:         "1267L",
+			ProblemURL:      "https://codeforces.com/contest/1267/problem/L",
+			ContestID:       "1267",
 			Language:        "GNU C++17",
 			Verdict:         "Wrong answer on test 1",
+			VerdictKind:     "Wrong answer",
+			VerdictTest:     1,
 			ParticipantType: "Contestant",
 			SentTime:        time.Date(2019, 12, 01, 9, 18, 50, 0, time.UTC),
+			JudgedTime:      time.Date(2019, 12, 01, 9, 18, 50, 0, time.UTC),
+			Time:            30 * time.Millisecond,
 			URL:             "testurl",
 			Content: `#include <bits/stdc++.h>
 
@@ -103,12 +139,18 @@ int main()
 			Author: &SubmissionInfoAuthor{
 				Handle: "frodakcin",
 				Color:  colorClsMap["user-red"],
+				Rank:   "International Grandmaster",
+				URL:    "https://codeforces.com/profile/frodakcin",
 			},
 			Problem:         "1386A",
+			ProblemURL:      "https://codeforces.com/contest/1386/problem/A",
+			ContestID:       "1386",
 			Language:        "GNU C++11",
 			Verdict:         "Perfect result: 100 points",
+			VerdictKind:     "Perfect result: 100 points",
 			ParticipantType: "Practice",
 			SentTime:        time.Date(2020, 8, 22, 6, 8, 49, 0, time.UTC),
+			JudgedTime:      time.Date(2020, 8, 22, 6, 8, 49, 0, time.UTC),
 			URL:             "testurl",
 			Content: `#include <cstdio>
 #include <cassert>
@@ -170,6 +212,49 @@ int main()
 	})
 }
 
+func TestParseSubmissionNotJudged(t *testing.T) {
+	doc, err := loadHtmlTestFile("contest_1267_submission_66681791.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoRow := doc.FindMatcher(infoRowSelec).Eq(1).FindMatcher(infoCellSelec)
+	infoRow.Eq(infoRow.Length() - 2).SetText("")
+	f := Fetcher{
+		FetchPage: func(context.Context, string) (*goquery.Document, error) { return doc, nil },
+	}
+	got, err := f.Submission(context.Background(), "testurl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.JudgedTime.IsZero() || got.SentTime.IsZero() {
+		t.Fatalf("got sent time %v and judged time %v, want only the sent time",
+			got.SentTime, got.JudgedTime)
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	for _, test := range []struct {
+		html     string
+		wantKind string
+		wantTest int
+	}{
+		{`<span class="verdict-accepted">Accepted</span>`, "Accepted", 0},
+		{`<span>Wrong answer on pretest <span class="verdict-format-judged">12</span></span>`, "Wrong answer", 12},
+		{`<span>Time limit exceeded on test <span class="verdict-format-judged">3</span></span>`, "Time limit exceeded", 3},
+		{`<span>Running on test <span class="verdict-format-judged">5</span></span>`, "Running", 5},
+		{`<span>Compilation error</span>`, "Compilation error", 0},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		kind, testNum := parseVerdict(doc.Find("span").First())
+		if kind != test.wantKind || testNum != test.wantTest {
+			t.Errorf("%v: got %q, %v, want %q, %v", test.html, kind, testNum, test.wantKind, test.wantTest)
+		}
+	}
+}
+
 func TestParseSubmissionLocaleParamStripped(t *testing.T) {
 	expected := errors.New("expected")
 	f := Fetcher{
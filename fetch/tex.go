@@ -0,0 +1,248 @@
+package fetch
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/meooow25/cfspy/texmath"
+)
+
+// Codeforces delimits display math with $$$$$$ and inline math with $$$.
+var mathRe = regexp.MustCompile(`(?s)\$\$\$\$\$\$(.+?)\$\$\$\$\$\$|\$\$\$(.+?)\$\$\$`)
+
+// Relations typed as runes, spaced like the relation commands.
+var texRelations = map[string]bool{
+	"=": true, "<": true, ">": true, "≤": true, "≥": true, "≠": true, "≈": true, "≡": true,
+	"∼": true, "≪": true, "≫": true, "∈": true, "∉": true, "∋": true, "⊂": true, "⊆": true,
+	"⊃": true, "⊇": true, "∣": true, "→": true, "←": true, "⇒": true, "⇐": true, "⇔": true,
+}
+
+// Operators whose limits are followed by a space, as in "∑ᵢ₌₁ⁿ aᵢ".
+var texBigOperators = map[string]bool{"∑": true, "∏": true, "∫": true, "⋃": true, "⋂": true}
+
+var (
+	superscripts = makeScriptMap(
+		"0123456789+-=()abcdefghijklmnoprstuvwxyzABDEGHIJKLMNOPRTUVW",
+		"⁰¹²³⁴⁵⁶⁷⁸⁹⁺⁻⁼⁽⁾ᵃᵇᶜᵈᵉᶠᵍʰⁱʲᵏˡᵐⁿᵒᵖʳˢᵗᵘᵛʷˣʸᶻᴬᴮᴰᴱᴳᴴᴵᴶᴷᴸᴹᴺᴼᴾᴿᵀᵁⱽᵂ")
+	subscripts = makeScriptMap(
+		"0123456789+-=()aehijklmnoprstuvx",
+		"₀₁₂₃₄₅₆₇₈₉₊₋₌₍₎ₐₑₕᵢⱼₖₗₘₙₒₚᵣₛₜᵤᵥₓ")
+)
+
+func makeScriptMap(from, to string) map[rune]rune {
+	m := make(map[rune]rune)
+	toRunes := []rune(to)
+	for i, r := range []rune(from) {
+		m[r] = toRunes[i]
+	}
+	return m
+}
+
+// Replaces math in the text of the selection with Unicode text where possible, and with the TeX
+// source formatted as code otherwise. Text in code is left alone. Returns the display math
+// formulas, in TeX.
+func convertMath(selec *goquery.Selection) (displayMath []string) {
+	selec.Find("*").AddSelection(selec).Contents().Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) != "#text" || s.ParentsFiltered("pre, code").Length() > 0 {
+			return
+		}
+		text := s.Text()
+		matches := mathRe.FindAllStringSubmatchIndex(text, -1)
+		if len(matches) == 0 {
+			return
+		}
+		var b strings.Builder
+		last := 0
+		for _, match := range matches {
+			b.WriteString(html.EscapeString(text[last:match[0]]))
+			var tex string
+			if match[2] != -1 {
+				tex = text[match[2]:match[3]]
+				displayMath = append(displayMath, strings.TrimSpace(tex))
+			} else {
+				tex = text[match[4]:match[5]]
+			}
+			if converted, err := texToUnicode(tex); err == nil {
+				b.WriteString(html.EscapeString(converted))
+			} else {
+				b.WriteString("<code>" + html.EscapeString(strings.TrimSpace(tex)) + "</code>")
+			}
+			last = match[1]
+		}
+		b.WriteString(html.EscapeString(text[last:]))
+		s.ReplaceWithHtml(b.String())
+	})
+	return displayMath
+}
+
+// Converts common TeX math to Unicode text, such as `\sum_{i=1}^{n} a_i \le 10^9` to
+// "∑ᵢ₌₁ⁿ aᵢ ≤ 10⁹". Returns an error for constructs that cannot be shown as text.
+func texToUnicode(tex string) (string, error) {
+	p := texParser{texmath.NewParser(tex)}
+	s, err := p.parseSeq()
+	if err != nil {
+		return "", err
+	}
+	if p.Peek() != "" {
+		return "", fmt.Errorf("%w: unmatched }", texmath.ErrUnsupported)
+	}
+	return strings.Join(strings.Fields(s), " "), nil
+}
+
+type texParser struct {
+	texmath.Parser
+}
+
+// Parses atoms with their scripts until the end or a closing brace.
+func (p *texParser) parseSeq() (string, error) {
+	var b strings.Builder
+	for {
+		p.SkipSpace()
+		if token := p.Peek(); token == "" || token == "}" {
+			return b.String(), nil
+		}
+		atom, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		bigOp := texBigOperators[atom]
+		for {
+			p.SkipSpace()
+			op := p.Peek()
+			if op != "^" && op != "_" {
+				break
+			}
+			p.Next()
+			script, err := p.parseAtom()
+			if err != nil {
+				return "", err
+			}
+			if op == "^" {
+				atom += toScript(script, superscripts, "^")
+			} else {
+				atom += toScript(script, subscripts, "_")
+			}
+		}
+		if bigOp {
+			atom += " "
+		}
+		b.WriteString(atom)
+	}
+}
+
+// Parses a single rune, command or group.
+func (p *texParser) parseAtom() (string, error) {
+	p.SkipSpace()
+	token := p.Next()
+	switch {
+	case token == "":
+		return "", fmt.Errorf("%w: unexpected end", texmath.ErrUnsupported)
+	case token == "{":
+		return p.parseGroupRest()
+	case token == "}" || token == "^" || token == "_" || token == "&" || token == `\\`:
+		return "", fmt.Errorf("%w: unexpected %v", texmath.ErrUnsupported, token)
+	case texRelations[token]:
+		return " " + token + " ", nil
+	case !strings.HasPrefix(token, `\`) || len(token) == 1:
+		return token, nil
+	}
+
+	if sym, ok := texmath.Symbols[token]; ok {
+		if sym.Class == texmath.ClassRel {
+			return " " + sym.Text + " ", nil
+		}
+		return sym.Text, nil
+	}
+	if width, ok := texmath.Spaces[token]; ok {
+		if width > 0 {
+			return " ", nil
+		}
+		return "", nil
+	}
+	if texmath.Functions[token] {
+		return token[1:] + " ", nil
+	}
+	if texmath.TextCommands[token] {
+		return p.ParseRawGroup()
+	}
+	switch token {
+	case `\frac`, `\dfrac`, `\tfrac`:
+		num, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		den, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		return parenthesize(num) + "/" + parenthesize(den), nil
+	case `\binom`:
+		n, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		k, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		return "C(" + strings.TrimSpace(n) + ", " + strings.TrimSpace(k) + ")", nil
+	case `\sqrt`:
+		if p.Peek() == "[" {
+			return "", fmt.Errorf("%w: \\sqrt with index", texmath.ErrUnsupported)
+		}
+		arg, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		return "√" + parenthesize(arg), nil
+	case `\pmod`:
+		arg, err := p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+		return " (mod " + strings.TrimSpace(arg) + ")", nil
+	}
+	return "", fmt.Errorf("%w: %v", texmath.ErrUnsupported, token)
+}
+
+// Parses the rest of a group after the opening brace.
+func (p *texParser) parseGroupRest() (string, error) {
+	s, err := p.parseSeq()
+	if err != nil {
+		return "", err
+	}
+	if p.Next() != "}" {
+		return "", fmt.Errorf("%w: unmatched {", texmath.ErrUnsupported)
+	}
+	return s, nil
+}
+
+// Returns the script in superscript or subscript runes if they all exist, otherwise with the
+// given operator, like ^(n+1).
+func toScript(script string, scriptRunes map[rune]rune, op string) string {
+	script = strings.Join(strings.Fields(script), "")
+	var b strings.Builder
+	for _, r := range script {
+		scriptRune, ok := scriptRunes[r]
+		if !ok {
+			return op + parenthesize(script)
+		}
+		b.WriteRune(scriptRune)
+	}
+	return b.String()
+}
+
+// Wraps s in parentheses unless it is a single number or name.
+func parenthesize(s string) string {
+	s = strings.TrimSpace(s)
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return "(" + s + ")"
+		}
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-test/deep"
+)
+
+func TestTeXToUnicode(t *testing.T) {
+	for _, test := range []struct {
+		tex  string
+		want string
+	}{
+		{`1\le t\le 100`, "1 ≤ t ≤ 100"},
+		{`\sum_{i=1}^{n} a_i \le 10^9`, "∑ᵢ₌₁ⁿ aᵢ ≤ 10⁹"},
+		{`2 \cdot 10^5`, "2·10⁵"},
+		{`a_{i+1} \ne a_i`, "aᵢ₊₁ ≠ aᵢ"},
+		{`x^{y_1}`, "x^(y₁)"},
+		{`2^{q}`, "2^q"},
+		{`b_{\max}`, "bₘₐₓ"},
+		{`\alpha + \beta \geq \pi`, "α+β ≥ π"},
+		{`\frac{n}{2}`, "n/2"},
+		{`\frac{n+1}{2k}`, "(n+1)/2k"},
+		{`x \in \{1, 2, \ldots, n\}`, "x ∈ {1,2,…,n}"},
+		{`A \cup B \subseteq S`, "A∪B ⊆ S"},
+		{`\sqrt{n}`, "√n"},
+		{`\lfloor \frac{n}{2} \rfloor`, "⌊n/2⌋"},
+		{`\max(a, b) \bmod 10^9+7`, "max (a,b) mod 10⁹+7"},
+		{`\text{the answer}`, "the answer"},
+		{`\binom{n}{k}`, "C(n, k)"},
+		{`\left( a \right)`, "(a)"},
+	} {
+		got, err := texToUnicode(test.tex)
+		if err != nil {
+			t.Errorf("%v: %v", test.tex, err)
+		} else if got != test.want {
+			t.Errorf("%v: got %q, want %q", test.tex, got, test.want)
+		}
+	}
+
+	for _, tex := range []string{
+		`\overline{ab}`,
+		`\begin{cases} 1 & x \\ 2 \end{cases}`,
+		`\sqrt[3]{n}`,
+		`{a`,
+		`a}`,
+		`x^`,
+	} {
+		if got, err := texToUnicode(tex); err == nil {
+			t.Errorf("%v: got %q, want error", tex, got)
+		}
+	}
+}
+
+func TestContentMath(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<div><p>Find $$$a_i \le 10^9$$$ for all $$$i$$$.</p>` +
+			`<p>$$$$$$\sum_{i=1}^{n} a_i$$$$$$</p>` +
+			`<p>Also $$$\overline{x} < y$$$.</p>` +
+			`<pre><code>$$$x$$$</code></pre></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, displayMath := getContentAsMarkdown(doc.Find("div"))
+	want := "Find aᵢ ≤ 10⁹ for all i.\n\n" +
+		"∑ᵢ₌₁ⁿ aᵢ\n\n" +
+		"Also `\\overline{x} < y`.\n\n" +
+		"```\n$x$\n```"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if diff := deep.Equal(displayMath, []string{`\sum_{i=1}^{n} a_i`}); diff != nil {
+		t.Fatal(diff)
+	}
+}
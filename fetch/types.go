@@ -15,9 +15,15 @@ type ProblemURLMatch struct {
 
 // SubmissionURLMatch contains matched information for a submission URL.
 type SubmissionURLMatch struct {
-	URL       string
-	LineBegin int
-	LineEnd   int
+	URL          string
+	Lines        []LineRange // Selected lines, if any
+	ContextLines int         // Lines to show around the selected lines
+}
+
+// LineRange is a range of lines from Begin to End inclusive, numbered from 1.
+type LineRange struct {
+	Begin int
+	End   int
 }
 
 // ProfileURLMatch contains matched information for a profile URL.
@@ -30,18 +36,33 @@ type BlogInfo struct {
 	Title        string
 	Content      string
 	Images       []string
+	Math         []string // Display math formulas in the content, in TeX
 	CreationTime time.Time
 	AuthorHandle string
 	AuthorAvatar string
 	AuthorColor  int
 	Rating       int
 	URL          string
+
+	Tags          []string
+	CommentCount  int
+	RevisionCount int
+	Revision      int
+	LastEditTime  time.Time    // Zero if not edited or unknown
+	Contest       *BlogContest // The contest the blog is an announcement or tutorial for, if any
+}
+
+// BlogContest is a contest a blog is about.
+type BlogContest struct {
+	Name string
+	URL  string
 }
 
 // CommentInfo contains comment information.
 type CommentInfo struct {
 	Content       string
 	Images        []string
+	Math          []string // Display math formulas in the content, in TeX
 	BlogTitle     string
 	CreationTime  time.Time
 	AuthorHandle  string
@@ -51,6 +72,14 @@ type CommentInfo struct {
 	Revision      int
 	Rating        int
 	URL           string
+
+	// The comments this is a reply to, the direct parent first. Parents are the latest revision and
+	// don't have parents of their own set. Parents stop before the first that cannot be parsed,
+	// like a deleted comment.
+	Parents []*CommentInfo
+
+	// Number of direct replies.
+	ReplyCount int
 }
 
 // ProblemInfo contains problem information.
@@ -65,11 +94,15 @@ type ProblemInfo struct {
 type SubmissionInfoAuthor struct {
 	Handle string
 	Color  int
+	Rank   string // Like "Pupil" or "Unrated"
+	URL    string // The profile
 }
 
 // SubmissionInfoTeam contains submission author team information.
 type SubmissionInfoTeam struct {
+	ID      string
 	Name    string
+	URL     string
 	Authors []*SubmissionInfoAuthor
 }
 
@@ -83,12 +116,21 @@ type SubmissionInfo struct {
 	AuthorGhost string
 
 	Problem         string
+	ProblemURL      string
+	ContestID       string
 	Language        string
 	Verdict         string
+	VerdictKind     string // The verdict without the test, like "Wrong answer"
+	VerdictTest     int    // The test or pretest the verdict is on, 0 if none
 	ParticipantType string
 	SentTime        time.Time
+	JudgedTime      time.Time // Zero if not judged yet
 	Content         string
 	URL             string
+
+	// Both zero if not shown, as in some contests.
+	Time   time.Duration
+	Memory int // In KB
 }
 
 // ProfileInfo contains profile information.
@@ -100,4 +142,17 @@ type ProfileInfo struct {
 	Color     int
 	Avatar    string
 	URL       string
+
+	MaxRank      string // Empty if unrated
+	Contribution int
+	FriendOf     int // The number of users with the user as a friend
+
+	// Empty if not set by the user.
+	Organization string
+	City         string
+	Country      string
+
+	// Zero if not shown, the last visit is not shown for users online now.
+	Registered time.Time
+	LastVisit  time.Time
 }
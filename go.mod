@@ -13,5 +13,6 @@ require (
 	github.com/google/go-github/v32 v32.1.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/togatoga/goforces v0.0.0-20200804081705-45bb4957d135
+	golang.org/x/image v0.0.0-20201208152932-35266b937fa6
 	golang.org/x/oauth2 v0.0.0-20201109201403-9fd604954f58
 )
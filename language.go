@@ -0,0 +1,57 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/meooow25/cfspy/fetch"
+)
+
+// Rules classifying Codeforces language names by the file extension of the language, which is also
+// used as the code block language. Names change with every compiler update, like "GNU G++23 14.2"
+// or "Python 3.13", so the rules match the family and not the version. They are tried in order, so
+// C++ is matched before C, PascalABC.NET before C# and JavaScript before Java.
+var languageRules = []struct {
+	ext string
+	re  *regexp.Regexp
+}{
+	{"cpp", regexp.MustCompile(`(?i)c\+\+|g\+\+`)},
+	{"pas", regexp.MustCompile(`(?i)delphi|fpc|pascal`)},
+	{"cs", regexp.MustCompile(`(?i)c#|\.net`)},
+	{"c", regexp.MustCompile(`(?i)^(gnu |clang )?c(\d+|\b)`)},
+	{"js", regexp.MustCompile(`(?i)javascript|node\.?js|\bv8\b`)},
+	{"java", regexp.MustCompile(`(?i)^java\b`)},
+	{"kt", regexp.MustCompile(`(?i)^kotlin\b`)},
+	{"py", regexp.MustCompile(`(?i)^(python|pypy)\b`)},
+	{"rs", regexp.MustCompile(`(?i)^rust\b`)},
+	{"go", regexp.MustCompile(`(?i)^go\b`)},
+	{"hs", regexp.MustCompile(`(?i)^haskell\b`)},
+	{"ml", regexp.MustCompile(`(?i)^ocaml\b`)},
+	{"pl", regexp.MustCompile(`(?i)^perl\b`)},
+	{"php", regexp.MustCompile(`(?i)^php\b`)},
+	{"rb", regexp.MustCompile(`(?i)^ruby\b`)},
+	{"sc", regexp.MustCompile(`(?i)^scala\b`)},
+	{"d", regexp.MustCompile(`(?i)^d\b`)},
+}
+
+// File extensions of the languages detected by fetch.DetectCodeLanguage, where they differ.
+var codeLanguageToExt = map[string]string{
+	"kotlin": "kt",
+	"rust":   "rs",
+	"python": "py",
+}
+
+// Returns the file extension of a Codeforces language name, like "cpp" for "GNU C++17". If the
+// name is not recognized, such as "Unknown" for ghosts, the language is detected from the code.
+// Returns "" if still unknown.
+func languageExt(language, code string) string {
+	for _, rule := range languageRules {
+		if rule.re.MatchString(language) {
+			return rule.ext
+		}
+	}
+	lang := fetch.DetectCodeLanguage(code)
+	if ext, ok := codeLanguageToExt[lang]; ok {
+		return ext
+	}
+	return lang
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestLanguageExt(t *testing.T) {
+	for _, test := range []struct {
+		language string
+		code     string
+		want     string
+	}{
+		{"GNU C11", "", "c"},
+		{"GNU C", "", "c"},
+		{"Clang++20 Diagnostics", "", "cpp"},
+		{"GNU C++17 (64)", "", "cpp"},
+		{"GNU G++23 14.2 (64 bit, msys2)", "", "cpp"},
+		{"MS C++ 2017", "", "cpp"},
+		{"C# 10", "", "cs"},
+		{"Mono C#", "", "cs"},
+		{".NET Core C#", "", "cs"},
+		{"D", "", "d"},
+		{"Delphi", "", "pas"},
+		{"FPC", "", "pas"},
+		{"PascalABC.NET", "", "pas"},
+		{"Go", "", "go"},
+		{"Haskell", "", "hs"},
+		{"Java 21", "", "java"},
+		{"JavaScript", "", "js"},
+		{"Node.js", "", "js"},
+		{"Kotlin 1.9", "", "kt"},
+		{"Ocaml", "", "ml"},
+		{"Perl", "", "pl"},
+		{"PHP", "", "php"},
+		{"Python 3.13", "", "py"},
+		{"PyPy 3-64", "", "py"},
+		{"Ruby 3", "", "rb"},
+		{"Rust 2021", "", "rs"},
+		{"Scala", "", "sc"},
+		{"Unknown", "#include <cstdio>\nint main() {}", "cpp"},
+		{"Unknown", "fun main() {\n}", "kt"},
+		{"Unknown", "for i in range(n):\n    print(i)", "py"},
+		{"Unknown", "5\n1 2 3", ""},
+	} {
+		if got := languageExt(test.language, test.code); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.language, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The comment syntax of a family of languages.
+type commentSyntax struct {
+	line  []string    // Start a comment up to the end of the line
+	block [][2]string // Start and end a comment
+}
+
+var (
+	cComments       = &commentSyntax{line: []string{"//"}, block: [][2]string{{"/*", "*/"}}}
+	hashComments    = &commentSyntax{line: []string{"#"}}
+	haskellComments = &commentSyntax{line: []string{"--"}, block: [][2]string{{"{-", "-}"}}}
+	ocamlComments   = &commentSyntax{block: [][2]string{{"(*", "*)"}}}
+	pascalComments  = &commentSyntax{
+		line:  []string{"//"},
+		block: [][2]string{{"{", "}"}, {"(*", "*)"}},
+	}
+	phpComments = &commentSyntax{line: []string{"//", "#"}, block: [][2]string{{"/*", "*/"}}}
+)
+
+// Comment syntax by file extension from languageExt. Languages not here have C comments.
+var extToComments = map[string]*commentSyntax{
+	"py":  hashComments,
+	"rb":  hashComments,
+	"pl":  hashComments,
+	"hs":  haskellComments,
+	"ml":  ocamlComments,
+	"pas": pascalComments,
+	"php": phpComments,
+}
+
+// Keywords and builtin types of the supported languages.
+var codeKeywords = makeSet(strings.Fields(`
+	auto bool break case catch char class const continue default delete do double else enum
+	extern false float for friend goto if inline int long namespace new nullptr operator private
+	protected public register return short signed sizeof static struct switch template this throw
+	true try typedef typename union unsigned using virtual void volatile while
+	abstract boolean byte extends final finally implements import instanceof interface native
+	null package super synchronized throws var
+	fun val when object companion data lateinit internal override open is in as
+	fn let mut impl trait pub mod use match loop crate self Self where move ref dyn
+	func go chan defer map range select type fallthrough
+	def elif except from global lambda nonlocal not or and pass raise with yield None True False
+	begin end then until elsif unless module require puts
+	procedure function program var div writeln readln
+	readonly sealed string decimal foreach out params
+`))
+
+func makeSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// The kind of a lexeme of code.
+type lexemeKind int
+
+// Kinds of lexemes.
+const (
+	plainLexeme lexemeKind = iota // Identifiers other than keywords
+	keywordLexeme
+	stringLexeme
+	numberLexeme
+	commentLexeme
+	punctLexeme
+)
+
+// A lexeme of code, which is a token or a comment.
+type codeLexeme struct {
+	kind       lexemeKind
+	start, end int // Byte offsets in the code
+	line       int // Line of the start, from 1
+}
+
+// Splits code into lexemes, dropping whitespace. Literals are strings and characters up to the
+// closing quote on the same line, and numbers. Everything else is punctuation, one character at
+// a time.
+func lexCode(code, ext string) []codeLexeme {
+	comments := extToComments[ext]
+	if comments == nil {
+		comments = cComments
+	}
+	var lexemes []codeLexeme
+	line := 1
+	// Returns the index past the first occurrence of s from i, or the end, counting lines.
+	skipPast := func(i int, s string) int {
+		j := strings.Index(code[i:], s)
+		if j == -1 {
+			j = len(code) - i
+		} else {
+			j += len(s)
+		}
+		line += strings.Count(code[i:i+j], "\n")
+		return i + j
+	}
+	isWord := func(c byte) bool {
+		return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+
+scan:
+	for i := 0; i < len(code); {
+		c := code[i]
+		if c == '\n' {
+			line++
+			i++
+			continue
+		}
+		if c < 0x80 && unicode.IsSpace(rune(c)) {
+			i++
+			continue
+		}
+		start, startLine := i, line
+		for _, marker := range comments.line {
+			if strings.HasPrefix(code[i:], marker) {
+				if j := strings.IndexByte(code[i:], '\n'); j != -1 {
+					i += j
+				} else {
+					i = len(code)
+				}
+				lexemes = append(lexemes, codeLexeme{commentLexeme, start, i, startLine})
+				continue scan
+			}
+		}
+		for _, block := range comments.block {
+			if strings.HasPrefix(code[i:], block[0]) {
+				i = skipPast(i+len(block[0]), block[1])
+				lexemes = append(lexemes, codeLexeme{commentLexeme, start, i, startLine})
+				continue scan
+			}
+		}
+		kind := punctLexeme
+		switch {
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(code) && code[j] != c && code[j] != '\n' {
+				if code[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(code) && code[j] == c {
+				i = j + 1
+				kind = stringLexeme
+			} else {
+				i++ // A lone quote, like in Haskell identifiers
+			}
+		case isWord(c):
+			for i < len(code) && isWord(code[i]) {
+				i++
+			}
+			switch {
+			case c >= '0' && c <= '9':
+				kind = numberLexeme
+			case codeKeywords[code[start:i]]:
+				kind = keywordLexeme
+			default:
+				kind = plainLexeme
+			}
+		default:
+			i++
+			for i < len(code) && code[i] >= 0x80 && code[i] < 0xC0 {
+				i++ // Rest of a UTF-8 character
+			}
+		}
+		lexemes = append(lexemes, codeLexeme{kind, start, i, line})
+	}
+	return lexemes
+}
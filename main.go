@@ -6,6 +6,8 @@ import (
 
 	"github.com/andersfylling/disgord"
 	"github.com/meooow25/cfspy/bot"
+	"github.com/meooow25/cfspy/codeimg"
+	"github.com/meooow25/cfspy/mathimg"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,6 +27,16 @@ func init() {
 
 func main() {
 	serverCountFeature := flag.Bool("scf", false, "install the server count feature")
+	widgetStorePath := flag.String(
+		"widgetstore", "", "file to persist widgets in, so that they work across restarts")
+	controlsPath := flag.String(
+		"controls", "", "file to save the preview controls configured in each server in")
+	mathImages := flag.Bool(
+		"mathimages", false, "render display math in blogs and comments to images")
+	flag.BoolVar(&numberFileSnippets,
+		"numberedfiles", false, "add line numbers to submission snippets sent as files")
+	codeImages := flag.String(
+		"codeimages", "", "send submission snippets as images with the given theme, dark or light")
 	flag.Parse()
 
 	if token == "" {
@@ -34,6 +46,25 @@ func main() {
 	logger.Info("------------ CFSpy starting ------------")
 	defer logger.Info("------------ CFSpy stopped ------------")
 
+	var err error
+	if widgetControls, err = newControlPolicyStore(*controlsPath); err != nil {
+		logger.Fatal("Loading preview controls failed: ", err)
+	}
+
+	if *mathImages {
+		mathRenderer = mathimg.NewRenderer(mathCacheSize)
+	}
+	if *codeImages != "" {
+		if snippetImageTheme = codeimg.Themes[*codeImages]; snippetImageTheme == nil {
+			logger.Fatal("Unknown code image theme: ", *codeImages)
+		}
+	}
+
+	var widgetStore bot.WidgetStore
+	if *widgetStorePath != "" {
+		widgetStore = bot.NewFileWidgetStore(*widgetStorePath)
+	}
+
 	b := bot.New(
 		bot.Info{
 			Config: disgord.Config{
@@ -50,12 +81,16 @@ func main() {
 			Prefix:      "c;",
 			Description: description,
 			SupportURL:  supportURL,
+			WidgetStore: widgetStore,
 		},
 	)
 
 	installPingCfCommand(b)
 	installFeatureInfoCommand(b)
 	installPingCommand(b)
+	installControlsCommand(b)
+	installSubmissionDiffCommand(b)
+	installSimilarityCommand(b)
 
 	installStatusFeature(b)
 
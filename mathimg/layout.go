@@ -0,0 +1,403 @@
+package mathimg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/meooow25/cfspy/strokefont"
+	"github.com/meooow25/cfspy/texmath"
+)
+
+// ErrUnsupported is returned for TeX that cannot be rendered.
+var ErrUnsupported = texmath.ErrUnsupported
+
+// A box of laid out math. The origin is at the left end of the baseline, the box extends asc
+// above and desc below it.
+type box struct {
+	w, asc, desc float64
+	segments     []strokefont.Segment
+}
+
+// Adds the segments of b to dst, scaled by s and moved by (dx, dy), and grows dst to fit.
+func (dst *box) place(b *box, dx, dy, s float64) {
+	for _, seg := range b.segments {
+		dst.segments = append(dst.segments, strokefont.Segment{
+			X1: dx + seg.X1*s, Y1: dy + seg.Y1*s, X2: dx + seg.X2*s, Y2: dy + seg.Y2*s, W: seg.W * s,
+		})
+	}
+	dst.w = math.Max(dst.w, dx+b.w*s)
+	dst.asc = math.Max(dst.asc, dy+b.asc*s)
+	dst.desc = math.Max(dst.desc, b.desc*s-dy)
+}
+
+func (dst *box) addLine(x1, y1, x2, y2 float64) {
+	dst.place(&box{segments: polyline(x1, y1, x2, y2)}, 0, 0, 1)
+}
+
+type atom struct {
+	box   *box
+	class texmath.Class
+}
+
+// Height of the math axis, on which fraction bars and operators like + are centered.
+const axis = 4
+
+// Ratio of the size of scripts to their base.
+const scriptScale = 0.7
+
+// Symbols drawn as similar runes that the font has.
+var lookalikes = map[rune]rune{'ϵ': 'ε', '∗': '*', '∣': '|', '⟨': '<', '⟩': '>'}
+
+// Large operators, drawn bigger and with limits below and above.
+var bigOperators = map[string]rune{`\sum`: 'Σ', `\prod`: 'Π'}
+
+// Width of 1 mu, 1/18 of an em, for the spacing commands.
+const muWidth = 0.56
+
+// Lays out TeX math.
+func layout(tex string) (*box, error) {
+	p := parser{texmath.NewParser(tex)}
+	b, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if p.Peek() != "" {
+		return nil, fmt.Errorf("%w: unmatched }", ErrUnsupported)
+	}
+	return b, nil
+}
+
+type parser struct {
+	texmath.Parser
+}
+
+// Parses atoms until the end or a closing brace and lays them out in a row.
+func (p *parser) parseList() (*box, error) {
+	var atoms []atom
+	for {
+		p.SkipSpace()
+		if token := p.Peek(); token == "" || token == "}" {
+			return hlist(atoms), nil
+		}
+		a, err := p.parseScripted()
+		if err != nil {
+			return nil, err
+		}
+		atoms = append(atoms, a)
+	}
+}
+
+// Parses an atom with its subscript and superscript, if any.
+func (p *parser) parseScripted() (atom, error) {
+	big, isBig := bigOperators[p.Peek()]
+	if isBig {
+		p.Next()
+	}
+	var base atom
+	if !isBig {
+		var err error
+		if base, err = p.parseAtom(); err != nil {
+			return atom{}, err
+		}
+	}
+
+	var sub, sup *box
+	for {
+		p.SkipSpace()
+		op := p.Peek()
+		if op != "^" && op != "_" {
+			break
+		}
+		p.Next()
+		script, err := p.parseAtom()
+		if err != nil {
+			return atom{}, err
+		}
+		if op == "^" {
+			sup = script.box
+		} else {
+			sub = script.box
+		}
+	}
+	if isBig {
+		return atom{bigOperator(big, sub, sup), texmath.ClassOp}, nil
+	}
+	if sub == nil && sup == nil {
+		return base, nil
+	}
+	return atom{scripts(base.box, sub, sup), base.class}, nil
+}
+
+// Parses a single rune, command or group.
+func (p *parser) parseAtom() (atom, error) {
+	p.SkipSpace()
+	token := p.Next()
+	switch {
+	case token == "":
+		return atom{}, fmt.Errorf("%w: unexpected end", ErrUnsupported)
+	case token == "{":
+		b, err := p.parseList()
+		if err != nil {
+			return atom{}, err
+		}
+		if p.Next() != "}" {
+			return atom{}, fmt.Errorf("%w: unmatched {", ErrUnsupported)
+		}
+		return atom{b, texmath.ClassOrd}, nil
+	case token == "}" || token == "^" || token == "_" || token == "&" || token == `\\`:
+		return atom{}, fmt.Errorf("%w: unexpected %v", ErrUnsupported, token)
+	case !strings.HasPrefix(token, `\`) || len(token) == 1:
+		r := []rune(token)[0]
+		b, err := text(string(r))
+		return atom{b, runeClass(r)}, err
+	}
+
+	if width, ok := texmath.Spaces[token]; ok {
+		return atom{&box{w: width * muWidth}, texmath.ClassOrd}, nil
+	}
+	if texmath.Functions[token] {
+		b, err := text(token[1:])
+		return atom{b, texmath.ClassOp}, err
+	}
+	if texmath.TextCommands[token] {
+		s, err := p.ParseRawGroup()
+		if err != nil {
+			return atom{}, err
+		}
+		b, err := text(s)
+		return atom{b, texmath.ClassOrd}, err
+	}
+
+	var args []*box
+	parseArgs := func(n int) error {
+		for i := 0; i < n; i++ {
+			arg, err := p.parseAtom()
+			if err != nil {
+				return err
+			}
+			args = append(args, arg.box)
+		}
+		return nil
+	}
+	switch token {
+	case `\frac`, `\dfrac`, `\tfrac`:
+		if err := parseArgs(2); err != nil {
+			return atom{}, err
+		}
+		return atom{fraction(args[0], args[1], true), texmath.ClassOrd}, nil
+	case `\binom`:
+		if err := parseArgs(2); err != nil {
+			return atom{}, err
+		}
+		return atom{parenthesized(fraction(args[0], args[1], false)), texmath.ClassOrd}, nil
+	case `\sqrt`:
+		if p.Peek() == "[" {
+			return atom{}, fmt.Errorf("%w: \\sqrt with index", ErrUnsupported)
+		}
+		if err := parseArgs(1); err != nil {
+			return atom{}, err
+		}
+		return atom{radical(args[0]), texmath.ClassOrd}, nil
+	case `\overline`, `\bar`:
+		if err := parseArgs(1); err != nil {
+			return atom{}, err
+		}
+		return atom{overline(args[0]), texmath.ClassOrd}, nil
+	case `\pmod`:
+		if err := parseArgs(1); err != nil {
+			return atom{}, err
+		}
+		mod, err := text("mod")
+		if err != nil {
+			return atom{}, err
+		}
+		inner := hlist([]atom{{mod, texmath.ClassOp}, {args[0], texmath.ClassOrd}})
+		return atom{parenthesized(inner), texmath.ClassOrd}, nil
+	case `\int`:
+		b, err := text("∫")
+		if err != nil {
+			return atom{}, err
+		}
+		var big box
+		big.place(b, 0, axis-axis*1.4, 1.4)
+		return atom{&big, texmath.ClassOp}, nil
+	}
+	if sym, ok := texmath.Symbols[token]; ok {
+		b, err := text(strings.TrimSpace(sym.Text))
+		return atom{b, sym.Class}, err
+	}
+	return atom{}, fmt.Errorf("%w: %v", ErrUnsupported, token)
+}
+
+func runeClass(r rune) texmath.Class {
+	switch r {
+	case '=', '<', '>':
+		return texmath.ClassRel
+	case '+', '-', '*', '/':
+		return texmath.ClassBin
+	case ',', ';':
+		return texmath.ClassPunct
+	}
+	return texmath.ClassOrd
+}
+
+// Space between runes of text.
+const letterSpacing = 0.3
+
+// Lays out text in a row.
+func text(s string) (*box, error) {
+	var b box
+	for _, r := range s {
+		if lookalike, ok := lookalikes[r]; ok {
+			r = lookalike
+		}
+		g, ok := strokefont.Lookup(r)
+		if !ok {
+			return nil, fmt.Errorf("%w: no glyph for %q", ErrUnsupported, r)
+		}
+		if b.w > 0 {
+			b.w += letterSpacing
+		}
+		b.place(&box{w: g.Advance, asc: 10, segments: g.Segments}, b.w, 0, 1)
+	}
+	b.asc = math.Max(b.asc, 7)
+	return &b, nil
+}
+
+// Lays out atoms in a row with the spacing TeX would use between them.
+func hlist(atoms []atom) *box {
+	space := func(class texmath.Class) float64 {
+		switch class {
+		case texmath.ClassBin:
+			return 1.8
+		case texmath.ClassRel:
+			return 2.5
+		}
+		return 0
+	}
+	var b box
+	prev := texmath.Class(-1)
+	for _, a := range atoms {
+		class := a.class
+		if class == texmath.ClassBin && (prev == -1 || prev == texmath.ClassBin ||
+			prev == texmath.ClassRel || prev == texmath.ClassOp || prev == texmath.ClassPunct) {
+			class = texmath.ClassOrd // Unary, as in -1
+		}
+		if prev != -1 {
+			b.w += math.Max(space(prev), space(class))
+		}
+		if prev == texmath.ClassPunct || prev == texmath.ClassOp && class == texmath.ClassOrd {
+			b.w += 1.5
+		}
+		b.place(a.box, b.w, 0, 1)
+		prev = class
+	}
+	return &b
+}
+
+func scripts(base, sub, sup *box) *box {
+	var b box
+	b.place(base, 0, 0, 1)
+	x := base.w + 0.3
+	if sup != nil {
+		y := math.Max(4.5, base.asc-4)
+		b.place(sup, x, y, scriptScale)
+	}
+	if sub != nil {
+		y := -math.Max(2.5, base.desc+1)
+		if sup != nil {
+			y = math.Min(y, 4.5-sub.asc*scriptScale-1.5)
+		}
+		b.place(sub, x, y, scriptScale)
+	}
+	b.w += 0.5
+	return &b
+}
+
+func bigOperator(r rune, sub, sup *box) *box {
+	const scale = 1.5
+	op, _ := text(string(r))
+	w := op.w * scale
+	if sub != nil {
+		w = math.Max(w, sub.w*scriptScale)
+	}
+	if sup != nil {
+		w = math.Max(w, sup.w*scriptScale)
+	}
+	var b box
+	b.place(op, (w-op.w*scale)/2, axis-5*scale, scale)
+	if sup != nil {
+		b.place(sup, (w-sup.w*scriptScale)/2, axis+5*scale+2+sup.desc*scriptScale, scriptScale)
+	}
+	if sub != nil {
+		b.place(sub, (w-sub.w*scriptScale)/2, axis-5*scale-1.5-sub.asc*scriptScale, scriptScale)
+	}
+	b.w = w + 0.5
+	return &b
+}
+
+// Returns num over den, with a bar between them if bar is set.
+func fraction(num, den *box, bar bool) *box {
+	const scale = 0.85
+	w := math.Max(num.w, den.w)*scale + 1
+	var b box
+	b.place(num, (w-num.w*scale)/2+0.5, axis+1.8+num.desc*scale, scale)
+	b.place(den, (w-den.w*scale)/2+0.5, axis-1.8-den.asc*scale, scale)
+	if bar {
+		b.addLine(0.5, axis, w+0.5, axis)
+	}
+	b.w = w + 1
+	return &b
+}
+
+// Returns b in parentheses that fit its height.
+func parenthesized(b *box) *box {
+	top, bottom := math.Max(b.asc, 10), math.Max(b.desc, 3)
+	cy, ry := (top-bottom)/2, (top+bottom)/2
+	var out box
+	out.place(&box{segments: arcSegments(3, cy, 2.5, ry, 120, 240)}, 0, 0, 1)
+	out.place(b, 3, 0, 1)
+	x := 3 + b.w
+	out.place(&box{segments: arcSegments(x, cy, 2.5, ry, 60, -60)}, 0, 0, 1)
+	out.w = x + 3
+	return &out
+}
+
+// Returns b under a square root sign.
+func radical(b *box) *box {
+	top, bottom := b.asc+1.5, -b.desc-0.5
+	var out box
+	out.place(&box{segments: polyline(0, 4, 1.2, 4.8, 3, bottom, 5, top, 5+b.w+1, top)}, 0, 0, 1)
+	out.place(b, 5.5, 0, 1)
+	out.w += 0.5
+	return &out
+}
+
+func overline(b *box) *box {
+	var out box
+	out.place(b, 0, 0, 1)
+	y := b.asc + 1.2
+	out.addLine(0.3, y, b.w-0.3, y)
+	return &out
+}
+
+func arcSegments(cx, cy, rx, ry, from, to float64) []strokefont.Segment {
+	points := strokefont.ArcPoints([2]float64{cx, cy}, [2]float64{rx, ry}, from, to)
+	var coords []float64
+	for _, pt := range points {
+		coords = append(coords, pt[0], pt[1])
+	}
+	return polyline(coords...)
+}
+
+// Returns the segments joining the points given as x1, y1, x2, y2, ...
+func polyline(coords ...float64) []strokefont.Segment {
+	var segments []strokefont.Segment
+	for i := 2; i+1 < len(coords); i += 2 {
+		segments = append(segments, strokefont.Segment{
+			X1: coords[i-2], Y1: coords[i-1], X2: coords[i], Y2: coords[i+1], W: strokefont.StrokeWidth,
+		})
+	}
+	return segments
+}
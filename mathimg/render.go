@@ -0,0 +1,154 @@
+// Package mathimg renders TeX math formulas to PNG images, in pure Go. It handles the common math
+// found on Codeforces, such as scripts, fractions, roots, big operators, Greek letters and
+// relations, and returns ErrUnsupported for anything else.
+package mathimg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"sync"
+
+	"github.com/meooow25/cfspy/strokefont"
+)
+
+const (
+	pixelsPerUnit = 2.2 // Capitals are 22 pixels tall
+	margin        = 8   // Pixels
+	formulaGap    = 6   // Pixels between formulas in one image
+	maxWidth      = 1600
+	maxHeight     = 1200
+)
+
+var errTooLarge = errors.New("Formula too large to render")
+
+// Renderer renders formulas, keeping the most recently rendered ones. It is safe for concurrent
+// use.
+type Renderer struct {
+	maxCached int
+
+	mu    sync.Mutex
+	cache map[string]*cached
+	order []string // Keys of cache, least recently added first
+}
+
+type cached struct {
+	img *image.Gray
+	err error
+}
+
+// NewRenderer returns a Renderer that caches up to maxCached formulas.
+func NewRenderer(maxCached int) *Renderer {
+	return &Renderer{
+		maxCached: maxCached,
+		cache:     make(map[string]*cached),
+	}
+}
+
+// Render renders a formula to a grayscale image of dark strokes on white.
+func (r *Renderer) Render(tex string) (*image.Gray, error) {
+	r.mu.Lock()
+	c, ok := r.cache[tex]
+	r.mu.Unlock()
+	if ok {
+		return c.img, c.err
+	}
+
+	img, err := render(tex)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cache[tex]; !ok && r.maxCached > 0 {
+		if len(r.order) == r.maxCached {
+			delete(r.cache, r.order[0])
+			r.order = r.order[1:]
+		}
+		r.cache[tex] = &cached{img, err}
+		r.order = append(r.order, tex)
+	}
+	return img, err
+}
+
+// RenderPNG renders formulas one below the other in a PNG image. Formulas that cannot be rendered
+// are left out, and the error of the first such formula is returned only if none can be rendered.
+func (r *Renderer) RenderPNG(formulas []string) ([]byte, error) {
+	var imgs []*image.Gray
+	var firstErr error
+	width, height := 0, 0
+	for _, tex := range formulas {
+		img, err := r.Render(tex)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		bounds := img.Bounds()
+		if height+bounds.Dy() > maxHeight {
+			break
+		}
+		imgs = append(imgs, img)
+		if bounds.Dx() > width {
+			width = bounds.Dx()
+		}
+		height += bounds.Dy()
+	}
+	if len(imgs) == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("No formulas to render")
+		}
+		return nil, firstErr
+	}
+
+	height += formulaGap * (len(imgs) - 1)
+	out := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	y := 0
+	for _, img := range imgs {
+		bounds := img.Bounds()
+		x := (width - bounds.Dx()) / 2
+		draw.Draw(out, image.Rect(x, y, x+bounds.Dx(), y+bounds.Dy()), img, bounds.Min, draw.Src)
+		y += bounds.Dy() + formulaGap
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Renders a formula to a new image.
+func render(tex string) (*image.Gray, error) {
+	b, err := layout(tex)
+	if err != nil {
+		return nil, err
+	}
+	width := int(math.Ceil(b.w*pixelsPerUnit)) + 2*margin
+	height := int(math.Ceil((b.asc+b.desc)*pixelsPerUnit)) + 2*margin
+	if width > maxWidth || height > maxHeight {
+		return nil, errTooLarge
+	}
+
+	// Coverage of each pixel by strokes, from 0 to 1.
+	coverage := make([]float64, width*height)
+	toPixel := func(x, y float64) (float64, float64) {
+		return x*pixelsPerUnit + margin, (b.asc-y)*pixelsPerUnit + margin
+	}
+	for _, seg := range b.segments {
+		x1, y1 := toPixel(seg.X1, seg.Y1)
+		x2, y2 := toPixel(seg.X2, seg.Y2)
+		strokefont.DrawSegment(coverage, width, height, x1, y1, x2, y2, seg.W*pixelsPerUnit)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i, c := range coverage {
+		img.Pix[i] = uint8(255 - math.Round(c*(255-inkLevel)))
+	}
+	return img, nil
+}
+
+// Gray level of strokes.
+const inkLevel = 30
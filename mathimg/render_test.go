@@ -0,0 +1,86 @@
+package mathimg
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	for _, tex := range []string{
+		`\sum_{i=1}^{n} a_i \le 10^9`,
+		`\frac{n(n+1)}{2} = \binom{n+1}{2}`,
+		`x = \sqrt{b^2 - 4ac} \cdot \pi`,
+		`\lfloor \frac{a}{b} \rfloor \in \{0, 1, \ldots, n\}`,
+		`\max_{1 \le i \le n} \overline{a_i} \pmod{998244353}`,
+		`\text{answer} \ne \alpha\beta\gamma\Delta\Omega`,
+		`\langle a \mid b \rangle \ast \epsilon \, x`,
+	} {
+		img, err := render(tex)
+		if err != nil {
+			t.Errorf("%v: %v", tex, err)
+			continue
+		}
+		bounds := img.Bounds()
+		if bounds.Dx() <= 2*margin || bounds.Dy() <= 2*margin {
+			t.Errorf("%v: got empty image of size %v", tex, bounds.Size())
+		}
+	}
+
+	for _, tex := range []string{
+		`\begin{cases} 1 & x \\ 2 \end{cases}`,
+		`\sqrt[3]{n}`,
+		`\hat{x}`,
+		`ж`,
+		`{a`,
+		`a}`,
+		`x^`,
+	} {
+		if _, err := render(tex); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%v: got error %v, want %v", tex, err, ErrUnsupported)
+		}
+	}
+}
+
+func TestRendererCache(t *testing.T) {
+	r := NewRenderer(2)
+	first, err := r.Render("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := r.Render("a"); again != first {
+		t.Fatal("got a new image, want the cached one")
+	}
+	r.Render("b")
+	r.Render("c") // Evicts a
+	if again, _ := r.Render("a"); again == first {
+		t.Fatal("got the cached image, want it evicted")
+	}
+	if len(r.cache) != 2 || len(r.order) != 2 {
+		t.Fatalf("got %v cached, want 2", len(r.cache))
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	r := NewRenderer(10)
+	a, _ := r.Render("a")
+	b, _ := r.Render(`\frac{1}{2}`)
+
+	data, err := r.RenderPNG([]string{"a", `\unknown`, `\frac{1}{2}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHeight := a.Bounds().Dy() + formulaGap + b.Bounds().Dy()
+	if got := img.Bounds().Dy(); got != wantHeight {
+		t.Fatalf("got height %v, want %v for the two formulas that render", got, wantHeight)
+	}
+
+	if _, err := r.RenderPNG([]string{`\unknown`}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("got error %v, want %v", err, ErrUnsupported)
+	}
+}
@@ -9,11 +9,15 @@ import (
 	"github.com/meooow25/cfspy/fetch"
 )
 
+// Widget kind for problem previews.
+const problemWidgetKind = "problem"
+
 // Installs the problem watcher feature. The bot watches for Codeforces problem links and responds
 // with an embed containing info about the problem.
 func installProblemFeature(bot *bot.Bot) {
 	bot.Client.Logger().Info("Setting up CF problem feature")
 	bot.OnMessageCreate(maybeHandleProblemURL)
+	bot.AddWidgetResumer(problemWidgetKind, resumeProblemWidget)
 }
 
 func maybeHandleProblemURL(ctx *bot.Context, evt *disgord.MessageCreate) {
@@ -32,20 +36,31 @@ func maybeHandleProblemURL(ctx *bot.Context, evt *disgord.MessageCreate) {
 func handleProblemURL(ctx *bot.Context, problemURL string) {
 	ctx.Logger.Info("Processing problem URL: ", problemURL)
 
-	problemInfo, err := fetch.Problem(context.Background(), problemURL)
+	params, err := makeProblemPreviewParams(ctx, problemURL)
 	if err != nil {
-		err = fmt.Errorf("Error fetching problem from %v: %w", problemURL, err)
 		ctx.Logger.Error(err)
 		respondWithError(ctx, err)
 		return
 	}
-
-	page := bot.NewPage("", makeProblemEmbed(problemInfo))
-	if err = respondWithOnePagePreview(ctx, page); err != nil {
+	if err = respondWithPreview(ctx, problemWidgetKind, problemURL, params); err != nil {
 		ctx.Logger.Error(fmt.Errorf("Error sending problem info: %w", err))
 	}
 }
 
+// Rebuilds a problem preview from its URL.
+func resumeProblemWidget(ctx *bot.Context, state *bot.WidgetState) (*bot.WidgetParams, error) {
+	return makeProblemPreviewParams(ctx, state.Source)
+}
+
+func makeProblemPreviewParams(ctx *bot.Context, problemURL string) (*bot.WidgetParams, error) {
+	problemInfo, err := fetch.Problem(context.Background(), problemURL)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching problem from %v: %w", problemURL, err)
+	}
+	page := bot.NewPage("", makeProblemEmbed(problemInfo))
+	return makeOnePagePreviewParams(ctx, page), nil
+}
+
 func makeProblemEmbed(p *fetch.ProblemInfo) *disgord.Embed {
 	return &disgord.Embed{
 		Title: p.Name,
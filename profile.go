@@ -3,17 +3,22 @@ package main
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/andersfylling/disgord"
 	"github.com/meooow25/cfspy/bot"
 	"github.com/meooow25/cfspy/fetch"
 )
 
+// Widget kind for profile previews.
+const profileWidgetKind = "profile"
+
 // Installs the profile watcher feature. The bot watches for Codeforces profile links and responds
 // with an embed containing some profile info.
 func installProfileFeature(bot *bot.Bot) {
 	bot.Client.Logger().Info("Setting up CF profile feature")
 	bot.OnMessageCreate(maybeHandleProfileURL)
+	bot.AddWidgetResumer(profileWidgetKind, resumeProfileWidget)
 }
 
 func maybeHandleProfileURL(ctx *bot.Context, evt *disgord.MessageCreate) {
@@ -31,30 +36,70 @@ func maybeHandleProfileURL(ctx *bot.Context, evt *disgord.MessageCreate) {
 func handleProfileUrl(ctx *bot.Context, url string) {
 	ctx.Logger.Info("Processing profile URL: ", url)
 
-	profileInfo, err := fetch.Profile(context.Background(), url)
+	params, err := makeProfilePreviewParams(ctx, url)
 	if err != nil {
-		err = fmt.Errorf("Error fetching profile from %v: %w", url, err)
 		ctx.Logger.Error(err)
 		respondWithError(ctx, err)
 		return
 	}
-
-	page := bot.NewPage("", makeProfileEmbed(profileInfo))
-	if err = respondWithOnePagePreview(ctx, page); err != nil {
+	if err = respondWithPreview(ctx, profileWidgetKind, url, params); err != nil {
 		ctx.Logger.Error(fmt.Errorf("Error sending profile info: %w", err))
 	}
 }
 
+// Rebuilds a profile preview from its URL.
+func resumeProfileWidget(ctx *bot.Context, state *bot.WidgetState) (*bot.WidgetParams, error) {
+	return makeProfilePreviewParams(ctx, state.Source)
+}
+
+func makeProfilePreviewParams(ctx *bot.Context, url string) (*bot.WidgetParams, error) {
+	profileInfo, err := fetch.Profile(context.Background(), url)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching profile from %v: %w", url, err)
+	}
+	page := bot.NewPage("", makeProfileEmbed(profileInfo))
+	return makeOnePagePreviewParams(ctx, page), nil
+}
+
 func makeProfileEmbed(p *fetch.ProfileInfo) *disgord.Embed {
 	desc := p.Rank
 	if p.Rating != 0 || p.Rank != "Unrated" && p.Rank != "Headquarters" {
-		desc += fmt.Sprintf("\nRating: %v (max. %v)", p.Rating, p.MaxRating)
+		if p.MaxRank != "" {
+			desc += fmt.Sprintf("\nRating: %v (max. %v, %v)", p.Rating, p.MaxRank, p.MaxRating)
+		} else {
+			desc += fmt.Sprintf("\nRating: %v (max. %v)", p.Rating, p.MaxRating)
+		}
 	}
-	return &disgord.Embed{
+	embed := &disgord.Embed{
 		Title:       p.Handle,
 		URL:         p.URL,
 		Thumbnail:   &disgord.EmbedThumbnail{URL: p.Avatar},
 		Color:       p.Color,
 		Description: desc,
 	}
+	addField := func(name, value string) {
+		embed.Fields = append(embed.Fields, &disgord.EmbedField{Name: name, Value: value, Inline: true})
+	}
+	addField("Contribution", fmt.Sprintf("%+d", p.Contribution))
+	addField("Friend of", fmt.Sprintf("%v users", p.FriendOf))
+	if p.Organization != "" {
+		addField("Organization", p.Organization)
+	}
+	var location []string
+	for _, part := range []string{p.City, p.Country} {
+		if part != "" {
+			location = append(location, part)
+		}
+	}
+	if len(location) > 0 {
+		addField("Location", strings.Join(location, ", "))
+	}
+	// Shown in the reader's time zone by Discord.
+	if !p.Registered.IsZero() {
+		addField("Registered", fmt.Sprintf("<t:%v:D>", p.Registered.Unix()))
+	}
+	if !p.LastVisit.IsZero() {
+		addField("Last visit", fmt.Sprintf("<t:%v:R>", p.LastVisit.Unix()))
+	}
+	return embed
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/meooow25/cfspy/fetch"
+)
+
+func TestMakeProfileEmbed(t *testing.T) {
+	embed := makeProfileEmbed(&fetch.ProfileInfo{
+		Handle:       "geranazavr555",
+		Rating:       1772,
+		MaxRating:    1864,
+		Rank:         "Headquarters",
+		MaxRank:      "Expert",
+		Contribution: 179,
+		FriendOf:     187,
+		Organization: "Poisk",
+		City:         "Stavropol",
+		Country:      "Russia",
+		Registered:   time.Unix(1400000000, 0),
+		LastVisit:    time.Unix(1600000000, 0),
+	})
+	if want := "Headquarters\nRating: 1772 (max. Expert, 1864)"; embed.Description != want {
+		t.Fatalf("got description %q, want %q", embed.Description, want)
+	}
+	want := []*disgord.EmbedField{
+		{Name: "Contribution", Value: "+179", Inline: true},
+		{Name: "Friend of", Value: "187 users", Inline: true},
+		{Name: "Organization", Value: "Poisk", Inline: true},
+		{Name: "Location", Value: "Stavropol, Russia", Inline: true},
+		{Name: "Registered", Value: "<t:1400000000:D>", Inline: true},
+		{Name: "Last visit", Value: "<t:1600000000:R>", Inline: true},
+	}
+	if len(embed.Fields) != len(want) {
+		t.Fatalf("got %v fields, want %v", len(embed.Fields), len(want))
+	}
+	for i, field := range embed.Fields {
+		if *field != *want[i] {
+			t.Fatalf("got field %+v, want %+v", *field, *want[i])
+		}
+	}
+
+	embed = makeProfileEmbed(&fetch.ProfileInfo{Rank: "Unrated", Country: "China"})
+	if embed.Description != "Unrated" {
+		t.Fatalf("got description %q, want only the rank", embed.Description)
+	}
+	if len(embed.Fields) != 3 || embed.Fields[2].Value != "China" {
+		t.Fatalf("got fields %v, want contribution, friends and country", embed.Fields)
+	}
+}
@@ -14,8 +14,9 @@ func respondWithError(ctx *bot.Context, err error) {
 func prepareCallbacks(ctx *bot.Context) (
 	msgCallback func(*disgord.Message),
 	delCallback func(*disgord.MessageReactionAdd),
-	allowOp func(*disgord.MessageReactionAdd) bool,
+	allowOp func(bot.WidgetOp, *disgord.MessageReactionAdd) bool,
 ) {
+	members := newMemberInfoCache()
 	return func(*disgord.Message) {
 			// This will fail without manage messages permission, that's fine.
 			go bot.SuppressEmbeds(ctx.Session, ctx.Message)
@@ -24,38 +25,49 @@ func prepareCallbacks(ctx *bot.Context) (
 			// This will fail without manage messages permission, that's fine.
 			go bot.UnsuppressEmbeds(ctx.Session, ctx.Message)
 		},
-		func(evt *disgord.MessageReactionAdd) bool {
-			// Allow only the author to control the widget.
-			return evt.UserID == ctx.Message.Author.ID
+		func(op bot.WidgetOp, evt *disgord.MessageReactionAdd) bool {
+			return allowWidgetOp(ctx, members, op, evt.UserID)
 		}
 }
 
-func respondWithOnePagePreview(
+// Sends the preview widget. The widget is persisted as the given kind with the given source if the
+// bot has a widget store, and can be rebuilt after a restart by the resumer for the kind.
+func respondWithPreview(ctx *bot.Context, kind, source string, params *bot.WidgetParams) error {
+	params.Kind = kind
+	params.Source = source
+	return ctx.SendWidget(params)
+}
+
+func makeOnePagePreviewParams(
 	ctx *bot.Context,
 	page *bot.Page,
 	files ...disgord.CreateMessageFileParams,
-) error {
-	getPage := func(int) *bot.Page { return page }
-	return respondWithMultiPagePreview(ctx, getPage, 1, files...)
+) *bot.WidgetParams {
+	return makeMultiPagePreviewParams(ctx, []*bot.Page{page}, files...)
 }
 
-func respondWithMultiPagePreview(
+func makeMultiPagePreviewParams(
 	ctx *bot.Context,
-	getPage func(int) *bot.Page,
-	numPages int,
+	pages []*bot.Page,
 	files ...disgord.CreateMessageFileParams,
-) error {
+) *bot.WidgetParams {
+	return makePreviewParams(ctx, &bot.Pages{
+		Get:       func(pageNum int) *bot.Page { return pages[pageNum-1] },
+		Total:     len(pages),
+		First:     1,
+		Files:     files,
+		Indicator: bot.PageIndicator,
+	})
+}
+
+func makePreviewParams(ctx *bot.Context, pages *bot.Pages) *bot.WidgetParams {
 	msgCallback, delCallback, allowOp := prepareCallbacks(ctx)
-	return ctx.SendWidget(&bot.WidgetParams{
-		Pages: &bot.Pages{
-			Get:   getPage,
-			Total: numPages,
-			First: numPages,
-			Files: files,
-		},
+	return &bot.WidgetParams{
+		Pages:       pages,
 		MsgCallback: msgCallback,
 		Lifetime:    time.Minute,
+		MaxLifetime: 10 * time.Minute,
 		DelCallback: delCallback,
 		AllowOp:     allowOp,
-	})
+	}
 }
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andersfylling/disgord"
+	"github.com/meooow25/cfspy/bot"
+	"github.com/meooow25/cfspy/fetch"
+)
+
+// The most submissions compared at once, every pair is compared.
+const maxSimilarSubmissions = 5
+
+// Matching regions shorter than this many tokens are ignored, since short sequences of tokens are
+// common to unrelated code.
+const minMatchTokens = 10
+
+// The most matching regions shown for a pair of submissions.
+const maxShownMatches = 3
+
+// Above this many cells, pairs of tokens of two submissions, the submissions are not compared, to
+// bound time and memory. Every pass of matchTokens goes over all cells.
+const maxMatchCells = 1 << 24
+
+// A normalized token of code and the line it is on, from 1.
+type codeToken struct {
+	text string
+	line int
+}
+
+// Tokenizes code, dropping comments and replacing identifiers other than keywords with "id".
+// Literals and punctuation are kept.
+func tokenizeCode(code, ext string) []codeToken {
+	var tokens []codeToken
+	for _, lexeme := range lexCode(code, ext) {
+		text := code[lexeme.start:lexeme.end]
+		switch lexeme.kind {
+		case commentLexeme:
+			continue
+		case plainLexeme:
+			text = "id"
+		}
+		tokens = append(tokens, codeToken{text, lexeme.line})
+	}
+	return tokens
+}
+
+// A matching region of two token sequences, at a in the first and b in the second.
+type tokenMatch struct {
+	a, b, length int
+}
+
+// Returns non-overlapping matching regions of at least minMatchTokens tokens, longest first, by
+// greedy string tiling. Every pass finds the maximal matches between tokens not yet in a match
+// and takes them longest first, skipping those overlapping an earlier one. Passes continue while
+// they find matches.
+func matchTokens(a, b []codeToken) []*tokenMatch {
+	var matches []*tokenMatch
+	markedA, markedB := make([]bool, len(a)), make([]bool, len(b))
+	for {
+		var found []*tokenMatch
+		// run[j] is the length of the match of unmarked tokens ending at the current a and b[j].
+		run, prevRun := make([]int32, len(b)+1), make([]int32, len(b)+1)
+		for i := range a {
+			run, prevRun = prevRun, run
+			for j := range b {
+				if markedA[i] || markedB[j] || a[i].text != b[j].text {
+					run[j+1] = 0
+					continue
+				}
+				run[j+1] = prevRun[j] + 1
+				length := int(run[j+1])
+				maximal := i+1 == len(a) || j+1 == len(b) || markedA[i+1] || markedB[j+1] ||
+					a[i+1].text != b[j+1].text
+				if maximal && length >= minMatchTokens {
+					found = append(found, &tokenMatch{i + 1 - length, j + 1 - length, length})
+				}
+			}
+		}
+		if len(found) == 0 {
+			break
+		}
+		sort.SliceStable(found, func(i, j int) bool { return found[i].length > found[j].length })
+	tiling:
+		for _, m := range found {
+			for k := 0; k < m.length; k++ {
+				if markedA[m.a+k] || markedB[m.b+k] {
+					continue tiling
+				}
+			}
+			for k := 0; k < m.length; k++ {
+				markedA[m.a+k], markedB[m.b+k] = true, true
+			}
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].length > matches[j].length })
+	return matches
+}
+
+// Returns the fraction of tokens of both sequences in the matches.
+func similarityScore(a, b []codeToken, matches []*tokenMatch) float64 {
+	if len(a)+len(b) == 0 {
+		return 0
+	}
+	covered := 0
+	for _, m := range matches {
+		covered += m.length
+	}
+	return float64(2*covered) / float64(len(a)+len(b))
+}
+
+// Installs the similar command, which scores how similar submissions are to each other.
+func installSimilarityCommand(b *bot.Bot) {
+	b.Client.Logger().Info("Setting up similar command")
+	b.AddCommand(&bot.Command{
+		ID:          "similar",
+		Usage:       "<submission url> <submission url>...",
+		Description: "Scores how similar submissions are, ignoring names, whitespace and comments",
+		Handler:     onSimilarity,
+	})
+}
+
+func onSimilarity(ctx *bot.Context) {
+	go func() {
+		if len(ctx.Args) < 3 || len(ctx.Args) > maxSimilarSubmissions+1 {
+			ctx.SendIncorrectUsageMsg()
+			return
+		}
+		var urls []string
+		for _, arg := range ctx.Args[1:] {
+			matches := fetch.ParseSubmissionURLs(arg)
+			if len(matches) != 1 {
+				ctx.SendIncorrectUsageMsg()
+				return
+			}
+			urls = append(urls, matches[0].URL)
+		}
+		ctx.Logger.Info("Processing submission similarity: ", strings.Join(urls, " "))
+
+		infos, err := fetchSubmissions(urls...)
+		if err != nil {
+			ctx.Logger.Error(err)
+			respondWithError(ctx, err)
+			return
+		}
+		if _, err = ctx.Send(makeSimilarityEmbed(infos)); err != nil {
+			ctx.Logger.Error(fmt.Errorf("Error sending similarity: %w", err))
+		}
+	}()
+}
+
+func makeSimilarityEmbed(infos []*fetch.SubmissionInfo) *disgord.Embed {
+	tokens := make([][]codeToken, len(infos))
+	var lines []string
+	for i, info := range infos {
+		tokens[i] = tokenizeCode(info.Content, languageExt(info.Language, info.Content))
+		lines = append(lines, fmt.Sprintf("[%v](%v)  •  %v  •  %v",
+			info.ID, info.URL, info.Language, info.Verdict))
+	}
+	embed := &disgord.Embed{
+		Author:      &disgord.EmbedAuthor{Name: "Submission similarity"},
+		Description: strings.Join(lines, "\n"),
+	}
+	for i := range infos {
+		for j := i + 1; j < len(infos); j++ {
+			field := makeSimilarityField(infos[i], infos[j], tokens[i], tokens[j])
+			embed.Fields = append(embed.Fields, field)
+		}
+	}
+	return embed
+}
+
+// Returns a field with the similarity score of two submissions and their longest matching
+// regions, as line ranges.
+func makeSimilarityField(
+	infoA, infoB *fetch.SubmissionInfo,
+	a, b []codeToken,
+) *disgord.EmbedField {
+	if len(a)*len(b) > maxMatchCells {
+		return &disgord.EmbedField{
+			Name:  fmt.Sprintf("%v and %v", infoA.ID, infoB.ID),
+			Value: "Too large to compare",
+		}
+	}
+	matches := matchTokens(a, b)
+	score := similarityScore(a, b, matches)
+	var lines []string
+	for _, m := range matches {
+		if len(lines) == maxShownMatches {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("Lines %v ↔ %v (%v tokens)",
+			lineSpan(a[m.a].line, a[m.a+m.length-1].line),
+			lineSpan(b[m.b].line, b[m.b+m.length-1].line),
+			m.length))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No matching regions")
+	}
+	return &disgord.EmbedField{
+		Name:  fmt.Sprintf("%v and %v: %.0f%% similar", infoA.ID, infoB.ID, 100*score),
+		Value: strings.Join(lines, "\n"),
+	}
+}
+
+func lineSpan(begin, end int) string {
+	if begin == end {
+		return fmt.Sprint(begin)
+	}
+	return fmt.Sprintf("%v-%v", begin, end)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meooow25/cfspy/fetch"
+)
+
+func tokenTexts(tokens []codeToken) string {
+	var texts []string
+	for _, token := range tokens {
+		texts = append(texts, token.text)
+	}
+	return strings.Join(texts, " ")
+}
+
+func TestTokenizeCode(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		code string
+		ext  string
+		want string
+	}{
+		{"c", "int n = 10; // count\n/* multi\nline */ return n;", "cpp",
+			"int id = 10 ; return id ;"},
+		{"python", "for i in range(n):  # loop\n    print('#')", "py",
+			"for id in range ( id ) : id ( '#' )"},
+		{"pascal", "begin { note } writeln(x) (* more *) end.", "pas",
+			"begin writeln ( id ) end ."},
+		{"unknown language", "x = y; // c style", "", "id = id ;"},
+		{"escaped quote", `s = "a\"b"; c = '\'';`, "cpp", `id = "a\"b" ; id = '\'' ;`},
+		{"unterminated comment", "x /* y", "cpp", "id"},
+	} {
+		if got := tokenTexts(tokenizeCode(test.code, test.ext)); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	tokens := tokenizeCode("a\n/*\n\n*/ b // c\nc", "cpp")
+	var lines []int
+	for _, token := range tokens {
+		lines = append(lines, token.line)
+	}
+	if len(lines) != 3 || lines[0] != 1 || lines[1] != 4 || lines[2] != 5 {
+		t.Fatalf("got lines %v, want [1 4 5]", lines)
+	}
+}
+
+const similarityTestCode = `#include <bits/stdc++.h>
+using namespace std;
+
+int solve(vector<int>& a) {
+    int best = 0, cur = 0;
+    for (int x : a) {
+        cur = max(x, cur + x);
+        best = max(best, cur);
+    }
+    return best;
+}
+
+int main() {
+    int n;
+    cin >> n;
+    vector<int> a(n);
+    for (auto& x : a) cin >> x;
+    cout << solve(a) << endl;
+}
+`
+
+func TestMakeSimilarityField(t *testing.T) {
+	// Renamed, reformatted and commented, with the functions swapped
+	renamed := strings.NewReplacer("solve", "kadane", "best", "ans", "cur", "s").
+		Replace(similarityTestCode)
+	parts := strings.SplitN(renamed, "\nint main", 2)
+	renamed = "// my solution\nint main" + parts[1] + "\n" + strings.Replace(parts[0], "\n", "\n\n", 1)
+
+	a := &fetch.SubmissionInfo{ID: "1", Language: "GNU C++17", Content: similarityTestCode}
+	b := &fetch.SubmissionInfo{ID: "2", Language: "GNU C++17", Content: renamed}
+	field := makeSimilarityField(a, b,
+		tokenizeCode(a.Content, "cpp"), tokenizeCode(b.Content, "cpp"))
+	if field.Name != "1 and 2: 100% similar" {
+		t.Fatalf("got %q, want all similar", field.Name)
+	}
+	if want := "Lines 1-11 ↔ 10-21 (68 tokens)\nLines 13-19 ↔ 2-8 (47 tokens)"; field.Value != want {
+		t.Fatalf("got\n%v\nwant\n%v", field.Value, want)
+	}
+
+	c := &fetch.SubmissionInfo{
+		ID:       "3",
+		Language: "Python 3",
+		Content:  "print(sum(map(int, input().split())))",
+	}
+	field = makeSimilarityField(a, c,
+		tokenizeCode(a.Content, "cpp"), tokenizeCode(c.Content, "py"))
+	if field.Name != "1 and 3: 0% similar" || field.Value != "No matching regions" {
+		t.Fatalf("got %q with %q, want no similarity", field.Name, field.Value)
+	}
+
+	large := make([]codeToken, maxMatchCells/len(tokenizeCode(a.Content, "cpp"))+1)
+	for i := range large {
+		large[i] = codeToken{"id", 1}
+	}
+	field = makeSimilarityField(a, c, tokenizeCode(a.Content, "cpp"), large)
+	if field.Name != "1 and 3" || field.Value != "Too large to compare" {
+		t.Fatalf("got %q with %q, want too large", field.Name, field.Value)
+	}
+}
@@ -0,0 +1,33 @@
+package strokefont
+
+import "math"
+
+// DrawSegment draws a segment with ends (x1, y1) and (x2, y2) and the given width, all in pixels,
+// on a coverage map of a width by height image. Coverage of a pixel by strokes goes from 0 to 1,
+// and overlapping strokes take the maximum. Strokes are at least a pixel wide, antialiased.
+func DrawSegment(coverage []float64, width, height int, x1, y1, x2, y2, strokeWidth float64) {
+	halfWidth := math.Max(strokeWidth, 1.2) / 2
+	minX := int(math.Max(math.Floor(math.Min(x1, x2)-halfWidth-1), 0))
+	maxX := int(math.Min(math.Ceil(math.Max(x1, x2)+halfWidth+1), float64(width-1)))
+	minY := int(math.Max(math.Floor(math.Min(y1, y2)-halfWidth-1), 0))
+	maxY := int(math.Min(math.Ceil(math.Max(y1, y2)+halfWidth+1), float64(height-1)))
+	for py := minY; py <= maxY; py++ {
+		for px := minX; px <= maxX; px++ {
+			d := distToSegment(float64(px)+0.5, float64(py)+0.5, x1, y1, x2, y2)
+			c := math.Min(math.Max(halfWidth+0.5-d, 0), 1)
+			if i := py*width + px; c > coverage[i] {
+				coverage[i] = c
+			}
+		}
+	}
+}
+
+// Returns the distance from (px, py) to the segment with ends (x1, y1) and (x2, y2).
+func distToSegment(px, py, x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	t := 0.0
+	if lenSq := dx*dx + dy*dy; lenSq > 0 {
+		t = math.Min(math.Max(((px-x1)*dx+(py-y1)*dy)/lenSq, 0), 1)
+	}
+	return math.Hypot(px-(x1+t*dx), py-(y1+t*dy))
+}
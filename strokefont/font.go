@@ -0,0 +1,259 @@
+// Package strokefont is a small vector font drawn with strokes, bundled in the source so that
+// images can be rendered in pure Go. It covers printable ASCII, Greek letters and common math
+// symbols.
+package strokefont
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Glyphs are drawn with strokes on a grid where the baseline is at y = 0, lowercase letters reach
+// y = 7 and capitals y = 10, with y going up. Each glyph is an advance width followed by strokes
+// separated by ";". A stroke is a polyline "M x,y x,y ..." or an elliptical arc
+// "A cx,cy rx,ry from,to" with angles in degrees, drawn counterclockwise if to > from.
+var glyphSpecs = map[rune]string{
+	'0': "6; A 3,5 2.5,5 0,360",
+	'1': "6; M 1.5,8 3.5,10 3.5,0; M 1.5,0 5.5,0",
+	'2': "6; A 3,7.5 2.5,2.5 160,-30; M 5.2,6.2 0.5,0 5.5,0",
+	'3': "6; A 3,7.6 2.3,2.4 150,-90; A 3,2.6 2.6,2.6 90,-150",
+	'4': "6; M 4.5,0 4.5,10 0.5,3 5.5,3",
+	'5': "6; M 5.2,10 1,10 0.6,5.5; A 3,3.2 2.6,3.2 135,-150",
+	'6': "6; A 3,3 2.5,3 0,360; M 0.5,3 0.9,7 2.5,9.6 4.5,10",
+	'7': "6; M 0.5,10 5.5,10 2,0",
+	'8': "6; A 3,7.7 2.2,2.3 0,360; A 3,2.7 2.6,2.7 0,360",
+	'9': "6; A 3,7 2.5,3 0,360; M 5.5,7 5.1,3 3.5,0.4 1.5,0",
+
+	'a': "6.5; A 3,3.5 2.5,3.5 0,360; M 5.5,7 5.5,0",
+	'b': "6.5; M 0.5,10 0.5,0; A 3,3.5 2.5,3.5 0,360",
+	'c': "6; A 3.25,3.5 2.75,3.5 45,315",
+	'd': "6.5; A 3,3.5 2.5,3.5 0,360; M 5.5,10 5.5,0",
+	'e': "6.5; M 0.5,3.5 5.7,3.5; A 3.1,3.5 2.6,3.5 0,315",
+	'f': "4.5; M 2,0 2,8; A 4,8 2,2 180,30; M 0.5,7 4,7",
+	'g': "6.5; A 3,3.5 2.5,3.5 0,360; M 5.5,7 5.5,-1; A 3,-1 2.5,2 0,-160",
+	'h': "6.5; M 0.5,10 0.5,0; A 3,4.5 2.5,2.5 180,0; M 5.5,4.5 5.5,0",
+	'i': "2.5; M 1.2,7 1.2,0; M 1.2,9.4 1.2,9.6",
+	'j': "4; M 3,7 3,-1; A 1.5,-1 1.5,2 0,-150; M 3,9.4 3,9.6",
+	'k': "6; M 0.5,10 0.5,0; M 5,7 0.5,2.5; M 2.3,4.3 5.5,0",
+	'l': "2.5; M 1.2,10 1.2,0",
+	'm': "9.5; M 0.5,7 0.5,0; A 2.5,5 2,2 180,0; M 4.5,5 4.5,0; A 6.5,5 2,2 180,0; M 8.5,5 8.5,0",
+	'n': "6.5; M 0.5,7 0.5,0; A 3,4.5 2.5,2.5 180,0; M 5.5,4.5 5.5,0",
+	'o': "6.5; A 3,3.5 2.5,3.5 0,360",
+	'p': "6.5; M 0.5,7 0.5,-3; A 3,3.5 2.5,3.5 0,360",
+	'q': "6.5; A 3,3.5 2.5,3.5 0,360; M 5.5,7 5.5,-3",
+	'r': "4.5; M 0.5,7 0.5,0; A 3.5,4 3,3 180,60",
+	's': "6; A 3,5.3 2.3,1.7 30,270; A 3,1.9 2.4,1.9 90,-150",
+	't': "4.5; M 2,9.5 2,1.5; A 3.5,1.5 1.5,1.5 180,300; M 0.5,7 4,7",
+	'u': "6.5; M 0.5,7 0.5,2.5; A 3,2.5 2.5,2.5 180,360; M 5.5,7 5.5,0",
+	'v': "6; M 0.5,7 3,0 5.5,7",
+	'w': "9.5; M 0.5,7 2.5,0 4.5,5 6.5,0 8.5,7",
+	'x': "6; M 0.5,7 5.5,0; M 5.5,7 0.5,0",
+	'y': "6; M 0.5,7 3,0; M 5.5,7 2,-3",
+	'z': "6; M 0.5,7 5.5,7 0.5,0 5.5,0",
+
+	'A': "7.5; M 0.5,0 3.75,10 7,0; M 1.6,3.5 5.9,3.5",
+	'B': "7; M 0.5,0 0.5,10 3.5,10; A 3.5,7.5 2.5,2.5 90,-90; M 0.5,5 3.8,5; A 3.8,2.5 2.7,2.5 90,-90; M 3.8,0 0.5,0",
+	'C': "7.5; A 4,5 3.5,5 45,315",
+	'D': "7.5; M 0.5,0 0.5,10 3,10; A 3,5 3.5,5 90,-90; M 3,0 0.5,0",
+	'E': "6.5; M 5.5,10 0.5,10 0.5,0 5.5,0; M 0.5,5 4.5,5",
+	'F': "6.5; M 5.5,10 0.5,10 0.5,0; M 0.5,5 4.5,5",
+	'G': "8; A 4,5 3.5,5 45,350; M 4.5,4.5 7.5,4.5 7.5,1.5",
+	'H': "7; M 0.5,0 0.5,10; M 6,0 6,10; M 0.5,5 6,5",
+	'I': "3.5; M 1.75,0 1.75,10; M 0.5,10 3,10; M 0.5,0 3,0",
+	'J': "6; M 5.5,10 5.5,3; A 3,3 2.5,3 0,-180",
+	'K': "6.5; M 0.5,0 0.5,10; M 5.5,10 0.5,4; M 2.3,6 6,0",
+	'L': "6; M 0.5,10 0.5,0 5.5,0",
+	'M': "8.5; M 0.5,0 0.5,10 4.25,3 8,10 8,0",
+	'N': "7; M 0.5,0 0.5,10 6,0 6,10",
+	'O': "8; A 4,5 3.5,5 0,360",
+	'P': "6.5; M 0.5,0 0.5,10 3.5,10; A 3.5,7.5 2.5,2.5 90,-90; M 3.5,5 0.5,5",
+	'Q': "8; A 4,5 3.5,5 0,360; M 4.5,2 7.5,-1",
+	'R': "7; M 0.5,0 0.5,10 3.5,10; A 3.5,7.5 2.5,2.5 90,-90; M 3.5,5 0.5,5; M 3,5 6,0",
+	'S': "6.5; A 3.2,7.5 2.7,2.5 20,270; A 3.2,2.5 2.7,2.5 90,-160",
+	'T': "7; M 0.5,10 6.5,10; M 3.5,10 3.5,0",
+	'U': "7; M 0.5,10 0.5,3; A 3.25,3 2.75,3 180,360; M 6,3 6,10",
+	'V': "7; M 0.5,10 3.5,0 6.5,10",
+	'W': "10; M 0.5,10 2.5,0 5,8 7.5,0 9.5,10",
+	'X': "7; M 0.5,10 6.5,0; M 6.5,10 0.5,0",
+	'Y': "7; M 0.5,10 3.5,5 6.5,10; M 3.5,5 3.5,0",
+	'Z': "7; M 0.5,10 6.5,10 0.5,0 6.5,0",
+
+	'+':  "7; M 0.5,4 6.5,4; M 3.5,1 3.5,7",
+	'-':  "7; M 0.5,4 6.5,4",
+	'=':  "7; M 0.5,5.2 6.5,5.2; M 0.5,2.8 6.5,2.8",
+	'<':  "7; M 6.5,8 0.5,4 6.5,0",
+	'>':  "7; M 0.5,8 6.5,4 0.5,0",
+	'(':  "4; A 4,3.5 3,7 120,240",
+	')':  "4; A 0,3.5 3,7 60,-60",
+	'[':  "4; M 3,10 1,10 1,-3 3,-3",
+	']':  "4; M 1,10 3,10 3,-3 1,-3",
+	'{':  "4; M 3.5,10 2.5,9.5 2.5,5 1,3.5 2.5,2 2.5,-2.5 3.5,-3",
+	'}':  "4; M 0.5,10 1.5,9.5 1.5,5 3,3.5 1.5,2 1.5,-2.5 0.5,-3",
+	'|':  "2.5; M 1.25,10 1.25,-3",
+	',':  "2.5; M 1.5,0.5 1.5,0 0.5,-2",
+	'.':  "2.5; M 1.25,0 1.25,0.1",
+	'/':  "6; M 0.5,-2 5.5,10",
+	'!':  "2.5; M 1.25,10 1.25,3; M 1.25,0 1.25,0.1",
+	'\'': "2.5; M 1.5,10 1,7.5",
+	':':  "2.5; M 1.25,6 1.25,6.1; M 1.25,0 1.25,0.1",
+	';':  "2.5; M 1.25,6 1.25,6.1; M 1.5,0.5 1.5,0 0.5,-2",
+	'*':  "6; M 3,7 3,1; M 0.4,5.5 5.6,2.5; M 0.4,2.5 5.6,5.5",
+	'%':  "7; M 0.5,0 6.5,10; A 1.7,8.5 1.3,1.5 0,360; A 5.3,1.5 1.3,1.5 0,360",
+	'"':  "4; M 1.25,10 1.25,7.5; M 2.75,10 2.75,7.5",
+	'#':  "7; M 2.5,0 3,8; M 4.5,0 5,8; M 1,5.5 6.5,5.5; M 0.5,2.5 6,2.5",
+	'$':  "6.5; A 3.25,7.5 2.5,2 10,270; A 3.25,3.5 2.5,2 90,-170; M 3.25,10.5 3.25,0.5",
+	'&':  "7.5; M 7,0 1.5,7; A 3,8.3 1.6,1.7 -130,180; A 3.2,2.8 2.7,2.8 180,330",
+	'?':  "6; A 3,7.5 2.5,2.5 160,-70; M 3.8,5.2 3,4 3,3; M 3,0 3,0.1",
+	'@':  "10; A 5,3.5 1.8,2.5 0,360; M 6.8,6 6.8,2; A 8,2 1.2,1.5 180,360; A 5,3.5 4.5,5 0,330",
+	'\\': "6; M 0.5,10 5.5,-2",
+	'^':  "6; M 0.5,6.5 3,10 5.5,6.5",
+	'_':  "7; M 0.5,-1.5 6.5,-1.5",
+	'`':  "3; M 1,10 2,8",
+	'~':  "7; M 0.5,3.5 1.5,4.5 2.5,4.5 4.5,3.5 5.5,3.5 6.5,4.5",
+	' ':  "3.5",
+
+	'α': "7; A 3,3.5 2.5,3.5 20,340; M 6.5,7 5.2,2 6,0 7,0",
+	'β': "6.5; M 0.5,-3 0.5,7.5; A 2.5,7.5 2,2.5 180,-90; A 2.8,2.5 2.7,2.5 90,-90; M 2.8,0 0.5,1.5",
+	'γ': "6; M 0.5,7 3,1 5.5,7; M 3,1 2.5,-3",
+	'δ': "6.5; A 3,3 2.5,3 0,360; M 3.5,6 1.5,8 2.5,10 5.5,10",
+	'ε': "6; A 3.5,5.3 2.5,1.7 60,270; A 3.5,1.8 2.8,1.8 90,300",
+	'θ': "6.5; A 3,5 2.5,5 0,360; M 0.5,5 5.5,5",
+	'ι': "2.5; M 1.2,7 1.2,0",
+	'κ': "6; M 0.5,7 0.5,0; M 5,7 0.5,3; M 2,4 5.5,0",
+	'λ': "6.5; M 0.5,0 3,5; M 1.5,10 6,0",
+	'μ': "6.5; M 0.5,7 0.5,-3; A 3,2.5 2.5,2.5 180,360; M 5.5,7 5.5,0",
+	'ν': "6; M 0.5,7 3,0 5.5,7",
+	'π': "7; M 0.5,7 6.5,7; M 2,7 2,0; M 5,7 5,0",
+	'ρ': "6.5; A 3,3.5 2.5,3.5 0,360; M 0.5,3.5 0.5,-3",
+	'σ': "7; A 3,3.5 2.5,3.5 0,360; M 3,7 7,7",
+	'τ': "6.5; M 0.5,7 6,7; M 3.25,7 3.25,0",
+	'φ': "7; A 3.5,3.5 3,3.5 0,360; M 3.5,10 3.5,-3",
+	'χ': "6; M 0.5,7 5.5,-3; M 5.5,7 0.5,-3",
+	'ψ': "7; M 0.5,7 0.5,4; A 3.5,4 3,3 180,360; M 6.5,4 6.5,7; M 3.5,10 3.5,-3",
+	'ω': "9.5; M 0.5,6 0.5,2.5; A 2.5,2.5 2,2.5 180,360; M 4.5,2.5 4.5,4; A 6.5,2.5 2,2.5 180,360; M 8.5,2.5 8.5,6",
+	'η': "6.5; M 0.5,7 0.5,0; A 3,4.5 2.5,2.5 180,0; M 5.5,4.5 5.5,-3",
+	'Γ': "6; M 5.5,10 0.5,10 0.5,0",
+	'Δ': "7.5; M 0.5,0 3.75,10 7,0 0.5,0",
+	'Θ': "8; A 4,5 3.5,5 0,360; M 2,5 6,5",
+	'Λ': "7.5; M 0.5,0 3.75,10 7,0",
+	'Π': "7.5; M 0.5,0 0.5,10 7,10 7,0",
+	'Σ': "7; M 6.5,10 0.5,10 3.5,5 0.5,0 6.5,0",
+	'Φ': "7.5; M 3.75,10 3.75,0; A 3.75,5 3.25,3 0,360",
+	'Ω': "8; A 4,5.5 3.5,4.5 240,-60; M 0.5,0 2.3,0; M 5.7,0 7.5,0",
+
+	'≤': "7; M 6.5,8.5 0.5,5.5 6.5,2.5; M 0.5,0.5 6.5,0.5",
+	'≥': "7; M 0.5,8.5 6.5,5.5 0.5,2.5; M 0.5,0.5 6.5,0.5",
+	'≠': "7; M 0.5,5.2 6.5,5.2; M 0.5,2.8 6.5,2.8; M 2,0.5 5,7.5",
+	'≈': "7; M 0.5,5.5 2,6.3 3.5,5.5 5,4.7 6.5,5.5; M 0.5,3 2,3.8 3.5,3 5,2.2 6.5,3",
+	'≡': "7; M 0.5,6 6.5,6; M 0.5,4 6.5,4; M 0.5,2 6.5,2",
+	'·': "2.5; M 1.25,4 1.25,4.1",
+	'×': "7; M 1,1.5 6,6.5; M 6,1.5 1,6.5",
+	'±': "7; M 0.5,5 6.5,5; M 3.5,8 3.5,2; M 0.5,0 6.5,0",
+	'∞': "9.5; A 2.75,4 2.25,2 0,360; A 7,4 2,2 0,360",
+	'∈': "6.5; A 6,4 4.5,3.5 90,270; M 1.5,4 6,4",
+	'∉': "6.5; A 6,4 4.5,3.5 90,270; M 1.5,4 6,4; M 2,-1 5,9",
+	'→': "9.5; M 0.5,4 8.5,4; M 6,6 8.5,4 6,2",
+	'←': "9.5; M 9,4 1,4; M 3.5,6 1,4 3.5,2",
+	'⇒': "9.5; M 0.5,5 7.5,5; M 0.5,3 7.5,3; M 6,7.5 9,4 6,0.5",
+	'∪': "7; M 0.5,8 0.5,3; A 3.5,3 3,3 180,360; M 6.5,3 6.5,8",
+	'∩': "7; M 0.5,0 0.5,5; A 3.5,5 3,3 180,0; M 6.5,5 6.5,0",
+	'⊂': "7; M 6.5,8 4,8; A 4,4 3.5,4 90,270; M 4,0 6.5,0",
+	'⊆': "7; M 6.5,9 4,9; A 4,5.5 3.5,3.5 90,270; M 4,2 6.5,2; M 0.5,0 6.5,0",
+	'∅': "7; A 3.5,4 3,4 0,360; M 0.5,-1 6.5,9",
+	'∀': "7; M 0.5,10 3.5,0 6.5,10; M 1.7,6 5.3,6",
+	'∃': "6.5; M 0.5,10 5.5,10 5.5,0 0.5,0; M 1.5,5 5.5,5",
+	'¬': "7; M 0.5,5 6.5,5 6.5,2.5",
+	'∧': "7; M 0.5,0 3.5,8 6.5,0",
+	'∨': "7; M 0.5,8 3.5,0 6.5,8",
+	'⊕': "7.5; A 3.75,4 3.25,3.25 0,360; M 0.5,4 7,4; M 3.75,0.75 3.75,7.25",
+	'′': "2.5; M 2,10 1,7",
+	'…': "9; M 1.5,0 1.5,0.1; M 4.5,0 4.5,0.1; M 7.5,0 7.5,0.1",
+	'⋯': "9; M 1.5,4 1.5,4.1; M 4.5,4 4.5,4.1; M 7.5,4 7.5,4.1",
+	'⋮': "2.5; M 1.25,1 1.25,1.1; M 1.25,4.5 1.25,4.6; M 1.25,8 1.25,8.1",
+	'⌊': "4; M 1,10 1,-3 3.5,-3",
+	'⌋': "4; M 3,10 3,-3 0.5,-3",
+	'⌈': "4; M 3.5,10 1,10 1,-3",
+	'⌉': "4; M 0.5,10 3,10 3,-3",
+	'∫': "5; M 0.5,-3 1.5,-3.5 2.5,-2 2.5,9 3.5,10.5 4.5,10",
+}
+
+// Segment is a line segment with ends (X1, Y1) and (X2, Y2) and width W.
+type Segment struct {
+	X1, Y1, X2, Y2, W float64
+}
+
+// Glyph is the strokes of a rune, and the advance width to the next rune.
+type Glyph struct {
+	Advance  float64
+	Segments []Segment
+}
+
+// StrokeWidth is the width of strokes at normal size.
+const StrokeWidth = 0.9
+
+var glyphs = parseGlyphs(glyphSpecs)
+
+// Lookup returns the glyph of a rune, or false if the font has none.
+func Lookup(r rune) (*Glyph, bool) {
+	g, ok := glyphs[r]
+	return g, ok
+}
+
+func parseGlyphs(specs map[rune]string) map[rune]*Glyph {
+	parsed := make(map[rune]*Glyph)
+	for r, spec := range specs {
+		parts := strings.Split(spec, ";")
+		g := &Glyph{Advance: mustParseFloat(parts[0])}
+		for _, part := range parts[1:] {
+			fields := strings.Fields(part)
+			var points [][2]float64
+			switch fields[0] {
+			case "M":
+				for _, field := range fields[1:] {
+					points = append(points, parsePoint(field))
+				}
+			case "A":
+				center, radii, angles := parsePoint(fields[1]), parsePoint(fields[2]), parsePoint(fields[3])
+				points = ArcPoints(center, radii, angles[0], angles[1])
+			default:
+				panic("bad glyph spec: " + spec)
+			}
+			for i := 1; i < len(points); i++ {
+				g.Segments = append(g.Segments, Segment{
+					points[i-1][0], points[i-1][1], points[i][0], points[i][1], StrokeWidth,
+				})
+			}
+		}
+		parsed[r] = g
+	}
+	return parsed
+}
+
+// ArcPoints approximates an elliptical arc with points joined by line segments.
+func ArcPoints(center, radii [2]float64, from, to float64) [][2]float64 {
+	const step = 15.0 // Degrees
+	n := int(math.Ceil(math.Abs(to-from) / step))
+	var points [][2]float64
+	for i := 0; i <= n; i++ {
+		angle := (from + (to-from)*float64(i)/float64(n)) * math.Pi / 180
+		points = append(points, [2]float64{
+			center[0] + radii[0]*math.Cos(angle),
+			center[1] + radii[1]*math.Sin(angle),
+		})
+	}
+	return points
+}
+
+func parsePoint(s string) [2]float64 {
+	parts := strings.Split(s, ",")
+	return [2]float64{mustParseFloat(parts[0]), mustParseFloat(parts[1])}
+}
+
+func mustParseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
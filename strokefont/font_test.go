@@ -0,0 +1,16 @@
+package strokefont
+
+import "testing"
+
+func TestPrintableASCII(t *testing.T) {
+	for r := rune(' '); r <= '~'; r++ {
+		g, ok := Lookup(r)
+		if !ok {
+			t.Errorf("no glyph for %q", r)
+			continue
+		}
+		if g.Advance <= 0 || (r != ' ') != (len(g.Segments) > 0) {
+			t.Errorf("got glyph for %q with advance %v and %v segments", r, g.Advance, len(g.Segments))
+		}
+	}
+}
@@ -1,81 +1,77 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/andersfylling/disgord"
 	"github.com/meooow25/cfspy/bot"
+	"github.com/meooow25/cfspy/codeimg"
 	"github.com/meooow25/cfspy/fetch"
 )
 
-var languageNameToExt = map[string]string{
-	// Available in the filter options on the status page of contests, as of 2022-10-12.
-	"GNU C11":               "c",
-	"Clang++20 Diagnostics": "cpp",
-	"Clang++17 Diagnostics": "cpp",
-	"GNU C++14":             "cpp",
-	"GNU C++17":             "cpp",
-	"GNU C++20 (64)":        "cpp",
-	"MS C++ 2017":           "cpp",
-	"GNU C++17 (64)":        "cpp",
-	"C# 8":                  "cs",
-	"C# 10":                 "cs",
-	"Mono C#":               "cs",
-	"D":                     "d",
-	"Go":                    "go",
-	"Haskell":               "hs",
-	"Java 11":               "java",
-	"Java 17":               "java",
-	"Java 8":                "java",
-	"Kotlin 1.4":            "kt",
-	"Kotlin 1.5":            "kt",
-	"Kotlin 1.6":            "kt",
-	"Ocaml":                 "ml",
-	"Delphi":                "pas",
-	"FPC":                   "pas",
-	"PascalABC.NET":         "pas",
-	"Perl":                  "pl",
-	"PHP":                   "php",
-	"Python 2":              "py",
-	"Python 3":              "py",
-	"PyPy 2":                "py",
-	"PyPy 3":                "py",
-	"PyPy 3-64":             "py",
-	"Ruby 3":                "rb",
-	"Rust 2021":             "rs",
-	"Scala":                 "sc",
-	"JavaScript":            "js",
-	"Node.js":               "js",
-
-	// Some old ones. I have no way of getting an exhaustive list of these removed names.
-	"GNU C":        "c",
-	"GNU C++":      "cpp",
-	"GNU C++0x":    "cpp",
-	"Java 6":       "java",
-	"Java 7":       "java",
-	"GNU C++11":    "cpp",
-	"MS C++":       "cpp",
-	"Kotlin":       "kt",
-	"Ruby":         "rb",
-	".NET Core C#": "cs",
-	"Rust":         "rs",
-}
-
 // The number of lines beyond which the snippet is sent as a file instead of message text.
 // This is because Discord's new file previews are nice and collapsible, taking up less space
 // compared to a wall of text in the message body.
 // The value below is chosen because it seems reasonable to me.
 const maxSnippetMsgLines = 30
 
+// The most lines of context shown around selected lines.
+const maxContextLines = 10
+
+// Separates the ranges of lines in a snippet.
+const snippetElision = "⋮"
+
+// Whether snippets sent as files have line numbers like snippets in messages, or are plain code.
+var numberFileSnippets bool
+
+// The theme of snippets sent as images, nil if snippets are sent as text. Snippets too large for
+// an image are sent as text anyway.
+var snippetImageTheme *codeimg.Theme
+
+// The kinds of spans in snippet images for kinds of lexemes.
+var lexemeSpanKinds = map[lexemeKind]codeimg.Kind{
+	plainLexeme:   codeimg.Plain,
+	keywordLexeme: codeimg.Keyword,
+	stringLexeme:  codeimg.String,
+	numberLexeme:  codeimg.Number,
+	commentLexeme: codeimg.Comment,
+	punctLexeme:   codeimg.Punct,
+}
+
 var (
 	errSelectionEmpty = errors.New("Selected lines are empty")
 	errMissingAuthor  = errors.New("Missing author details in submission info")
 )
 
+// Verdicts, without the test, of submissions that are still being judged.
+var judgingVerdicts = map[string]bool{
+	"In queue":  true,
+	"Compiling": true,
+	"Running":   true,
+	"Judging":   true,
+	"Testing":   true,
+	"Pending":   true,
+}
+
+// Submissions being judged are polled for the verdict, first after the min delay and then at
+// doubling intervals up to the max delay. The delay starts over when the verdict changes.
+const (
+	minVerdictPollDelay = 3 * time.Second
+	maxVerdictPollDelay = 30 * time.Second
+)
+
+// Widget kind for submission previews.
+const submissionWidgetKind = "submission"
+
 const (
 	ghostColor = 0x999999 // Same color as text on CF
 	teamColor  = 0x666666 // Darker than ghosts
@@ -83,18 +79,28 @@ const (
 
 // Installs the submission watcher feature. The bot watches for Codeforces submission links and
 // responds with an embed containing info about the submission. If the submission has line numbers,
-// responds with the lines.
+// responds with the lines. If there are two submission links, responds with a diff between them.
 func installSubmissionFeature(bot *bot.Bot) {
 	bot.Client.Logger().Info("Setting up CF submission feature")
 	bot.OnMessageCreate(maybeHandleSubmissionURL)
+	bot.AddWidgetResumer(submissionWidgetKind, resumeSubmissionWidget)
 }
 
 func maybeHandleSubmissionURL(ctx *bot.Context, evt *disgord.MessageCreate) {
 	go func() {
+		if strings.HasPrefix(evt.Message.Content, ctx.Bot.Info.Prefix) {
+			return // Commands like diff handle their own links
+		}
 		submissionURLMatches := fetch.ParseSubmissionURLs(evt.Message.Content)
 		if len(submissionURLMatches) == 0 {
 			return
 		}
+		// Two plain submission links are compared
+		if len(submissionURLMatches) == 2 &&
+			len(submissionURLMatches[0].Lines) == 0 && len(submissionURLMatches[1].Lines) == 0 {
+			handleSubmissionDiff(ctx, submissionURLMatches[0].URL, submissionURLMatches[1].URL)
+			return
+		}
 		first := submissionURLMatches[0]
 		handleSubmissionURL(ctx, first)
 	}()
@@ -104,61 +110,181 @@ func maybeHandleSubmissionURL(ctx *bot.Context, evt *disgord.MessageCreate) {
 func handleSubmissionURL(ctx *bot.Context, match *fetch.SubmissionURLMatch) {
 	ctx.Logger.Info("Processing submission URL: ", match.URL)
 
-	submissionInfo, err := fetch.Submission(context.Background(), match.URL)
+	params, err := makeSubmissionPreviewParams(ctx, match)
 	if err != nil {
-		err = fmt.Errorf("Error fetching submission from %v: %w", match.URL, err)
 		ctx.Logger.Error(err)
 		respondWithError(ctx, err)
 		return
 	}
+	if err = respondWithPreview(ctx, submissionWidgetKind, match.URL, params); err != nil {
+		ctx.Logger.Error(fmt.Errorf("Error sending problem info: %w", err))
+	}
+}
+
+// Rebuilds a submission preview from its URL, which includes any line numbers.
+func resumeSubmissionWidget(ctx *bot.Context, state *bot.WidgetState) (*bot.WidgetParams, error) {
+	submissionURLMatches := fetch.ParseSubmissionURLs(state.Source)
+	if len(submissionURLMatches) == 0 {
+		return nil, fmt.Errorf("Not a submission URL: %v", state.Source)
+	}
+	return makeSubmissionPreviewParams(ctx, submissionURLMatches[0])
+}
+
+func makeSubmissionPreviewParams(
+	ctx *bot.Context,
+	match *fetch.SubmissionURLMatch,
+) (*bot.WidgetParams, error) {
+	submissionInfo, err := fetch.Submission(context.Background(), match.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching submission from %v: %w", match.URL, err)
+	}
 
 	content, embed, file, err :=
-		makeSubmissionResponse(submissionInfo, match.LineBegin, match.LineEnd)
+		makeSubmissionResponse(submissionInfo, match.Lines, match.ContextLines)
 	if err != nil {
-		ctx.Logger.Error(err)
-		respondWithError(ctx, err)
-		return
+		return nil, err
 	}
 
 	page := bot.NewPage(content, embed)
+	var params *bot.WidgetParams
 	if file != nil {
-		err = respondWithOnePagePreview(ctx, page, *file)
+		params = makeOnePagePreviewParams(ctx, page, *file)
 	} else {
-		err = respondWithOnePagePreview(ctx, page)
+		params = makeOnePagePreviewParams(ctx, page)
 	}
-	if err != nil {
-		ctx.Logger.Error(fmt.Errorf("Error sending problem info: %w", err))
+	if len(match.Lines) == 0 && submissionJudging(submissionInfo) {
+		// Judging can take minutes in a long queue, keep the preview around for the verdict.
+		params.Lifetime = params.MaxLifetime
+		params.Refresh = func(pollCtx context.Context, update func(int, *bot.Page)) {
+			trackVerdict(ctx, pollCtx, submissionInfo, update)
+		}
+	}
+	return params, nil
+}
+
+func submissionJudging(info *fetch.SubmissionInfo) bool {
+	return info.Verdict == "" || judgingVerdicts[info.VerdictKind]
+}
+
+// Polls the submission while it is being judged and updates the preview whenever the verdict
+// changes, until judging finishes or pollCtx is done.
+func trackVerdict(
+	ctx *bot.Context,
+	pollCtx context.Context,
+	info *fetch.SubmissionInfo,
+	update func(int, *bot.Page),
+) {
+	delay := minVerdictPollDelay
+	for submissionJudging(info) {
+		select {
+		case <-time.After(delay):
+		case <-pollCtx.Done():
+			return
+		}
+		newInfo, err := fetch.Submission(pollCtx, info.URL)
+		if err != nil {
+			if pollCtx.Err() == nil {
+				ctx.Logger.Error(fmt.Errorf("Error polling submission %v: %w", info.URL, err))
+			}
+			delay = nextPollDelay(delay)
+			continue
+		}
+		if newInfo.Verdict == info.Verdict {
+			delay = nextPollDelay(delay)
+			continue
+		}
+		info = newInfo
+		delay = minVerdictPollDelay
+		embed, err := makeSubmissionEmbed(info)
+		if err != nil {
+			ctx.Logger.Error(err)
+			return
+		}
+		if !submissionJudging(info) {
+			embed.Footer = &disgord.EmbedFooter{Text: "Judging finished"}
+		}
+		update(1, bot.NewPage("", embed))
+	}
+}
+
+func nextPollDelay(delay time.Duration) time.Duration {
+	if delay *= 2; delay > maxVerdictPollDelay {
+		delay = maxVerdictPollDelay
 	}
+	return delay
+}
+
+// Fetches the submissions concurrently.
+func fetchSubmissions(urls ...string) ([]*fetch.SubmissionInfo, error) {
+	infos := make([]*fetch.SubmissionInfo, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			infos[i], errs[i] = fetch.Submission(context.Background(), url)
+		}(i, url)
+	}
+	wg.Wait()
+	for i, url := range urls {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("Error fetching submission from %v: %w", url, errs[i])
+		}
+	}
+	return infos, nil
 }
 
 func makeSubmissionResponse(
 	info *fetch.SubmissionInfo,
-	lineBegin int,
-	lineEnd int,
+	lines []fetch.LineRange,
+	contextLines int,
 ) (string, *disgord.Embed, *disgord.CreateMessageFileParams, error) {
 	// No line numbers, show summary
-	if lineBegin == 0 {
+	if len(lines) == 0 {
 		embed, err := makeSubmissionEmbed(info)
 		return "", embed, nil, err
 	}
 
-	snippet, numLines, err := makeCodeSnippet(info.Content, lineBegin, lineEnd)
+	snippet, err := makeCodeSnippet(info.Content, lines, contextLines)
 	if err != nil {
 		return "", nil, nil, err
 	}
-	content := makeContent(snippet, info.Language)
+	// With context the selected lines are highlighted, which takes a diff code block instead of
+	// the language.
+	markSelected := contextLines > 0
+	ext := languageExt(info.Language, info.Content)
+	if snippetImageTheme != nil {
+		if img, err := codeimg.RenderPNG(makeSnippetImageLines(snippet, ext, markSelected),
+			snippetImageTheme); err == nil {
+			file := &disgord.CreateMessageFileParams{
+				Reader:   bytes.NewReader(img),
+				FileName: "snippet_" + info.ID + ".png",
+			}
+			return "", nil, file, nil
+		}
+	}
+	language := ext
+	if markSelected {
+		language = "diff"
+	}
+	content := makeContent(formatNumberedSnippet(snippet, markSelected), language)
 
 	// Content ok in a message, send
-	if numLines <= maxSnippetMsgLines && !bot.ContentTooLong(content) {
+	if snippetLineCount(snippet) <= maxSnippetMsgLines && !bot.ContentTooLong(content) {
 		return content, nil, nil, nil
 	}
 
 	// Content too large or ugly in a message, send as file
 	// The file size is never expected to be too large as Codeforces source limit is 64KB
 	// and Discord limit is 8MB
+	fileContent := formatPlainSnippet(snippet)
+	if numberFileSnippets {
+		fileContent = formatNumberedSnippet(snippet, false)
+	}
 	file := &disgord.CreateMessageFileParams{
-		Reader:   strings.NewReader(snippet),
-		FileName: makeFilename(info.ID, info.Language),
+		Reader:   strings.NewReader(fileContent),
+		FileName: makeFilename(info.ID, ext),
 	}
 	return "", nil, file, nil
 }
@@ -167,6 +293,8 @@ func makeSubmissionEmbed(s *fetch.SubmissionInfo) (*disgord.Embed, error) {
 	prefix := ""
 	if s.Verdict == "Accepted" || strings.HasPrefix(s.Verdict, "Perfect result") {
 		prefix = "✅ "
+	} else if submissionJudging(s) {
+		prefix = "⏳ "
 	}
 	var author string
 	var color int
@@ -178,11 +306,8 @@ func makeSubmissionEmbed(s *fetch.SubmissionInfo) (*disgord.Embed, error) {
 		author = s.AuthorGhost + " 👻"
 		color = ghostColor
 	case s.AuthorTeam != nil:
-		var handles []string
-		for _, author := range s.AuthorTeam.Authors {
-			handles = append(handles, author.Handle)
-		}
-		author = s.AuthorTeam.Name + ": " + strings.Join(handles, ", ")
+		// Members are in fields, a title with all of them can be too long.
+		author = s.AuthorTeam.Name
 		color = teamColor
 	default:
 		// not expected
@@ -199,15 +324,220 @@ func makeSubmissionEmbed(s *fetch.SubmissionInfo) (*disgord.Embed, error) {
 		Description: prefix + s.Verdict + " • " + s.ParticipantType + " • " + language,
 		Timestamp:   disgord.Time{Time: s.SentTime},
 	}
+	if s.AuthorTeam != nil {
+		embed.Fields = append(embed.Fields, makeTeamFields(s.AuthorTeam)...)
+	}
+	if s.ProblemURL != "" {
+		problem := fmt.Sprintf("[%v](%v)", s.Problem, s.ProblemURL)
+		if i := strings.Index(s.ProblemURL, "/problem/"); i != -1 && s.ContestID != "" {
+			problem += fmt.Sprintf(" in [contest %v](%v)", s.ContestID, s.ProblemURL[:i])
+		}
+		embed.Fields = append(embed.Fields, &disgord.EmbedField{Name: "Problem", Value: problem})
+	}
+	if s.Time > 0 || s.Memory > 0 {
+		embed.Fields = append(embed.Fields,
+			&disgord.EmbedField{
+				Name:   "Time",
+				Value:  fmt.Sprintf("%v ms", s.Time.Milliseconds()),
+				Inline: true,
+			},
+			&disgord.EmbedField{
+				Name:   "Memory",
+				Value:  fmt.Sprintf("%v KB", s.Memory),
+				Inline: true,
+			})
+	}
+	if !s.JudgedTime.IsZero() {
+		// Shown in the reader's time zone by Discord.
+		embed.Fields = append(embed.Fields, &disgord.EmbedField{
+			Name:   "Judged",
+			Value:  fmt.Sprintf("<t:%v:f>", s.JudgedTime.Unix()),
+			Inline: true,
+		})
+	}
 	return embed, nil
 }
 
-func makeCodeSnippet(code string, begin, end int) (snippet string, numLines int, err error) {
+// Returns a field linking the team followed by an inline field for each member, named by the rank
+// of the member.
+func makeTeamFields(team *fetch.SubmissionInfoTeam) []*disgord.EmbedField {
+	fields := []*disgord.EmbedField{{Name: "Team", Value: makeLink(team.Name, team.URL)}}
+	for _, member := range team.Authors {
+		rank := member.Rank
+		if rank == "" {
+			rank = "Member"
+		}
+		fields = append(fields, &disgord.EmbedField{
+			Name:   rank,
+			Value:  makeLink(member.Handle, member.URL),
+			Inline: true,
+		})
+	}
+	return fields
+}
+
+// Returns a markdown link with the text, or the text alone if there is no URL.
+func makeLink(text, url string) string {
+	if url == "" {
+		return text
+	}
+	return fmt.Sprintf("[%v](%v)", text, url)
+}
+
+// A range of lines of a snippet.
+type snippetRange struct {
+	begin    int      // Number of the first line
+	lines    []string // Dedented
+	selected []bool   // Whether each line was selected, as opposed to shown for context
+}
+
+// Returns the ranges of the snippet of code with the selected lines and contextLines lines around
+// them. Each range is dedented on its own.
+func makeCodeSnippet(
+	code string,
+	ranges []fetch.LineRange,
+	contextLines int,
+) ([]*snippetRange, error) {
 	code = strings.ReplaceAll(code, "\r\n", "\n")
 	lines := strings.Split(code, "\n")
-	begin, end = clamp(begin, 1, len(lines)), clamp(end, 1, len(lines))
-	lines = lines[begin-1 : end]
+	var snippet []*snippetRange
+	allEmpty := true
+	for _, r := range mergeLineRanges(ranges, clamp(contextLines, 0, maxContextLines), len(lines)) {
+		dedented, empty := dedent(lines[r.Begin-1 : r.End])
+		allEmpty = allEmpty && empty
+		selected := make([]bool, len(dedented))
+		for i := range selected {
+			selected[i] = lineSelected(r.Begin+i, ranges)
+		}
+		snippet = append(snippet, &snippetRange{begin: r.Begin, lines: dedented, selected: selected})
+	}
+	if allEmpty {
+		return nil, errSelectionEmpty
+	}
+	return snippet, nil
+}
+
+func lineSelected(lineNum int, ranges []fetch.LineRange) bool {
+	for _, r := range ranges {
+		if r.Begin <= lineNum && lineNum <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the snippet as plain code, with ranges separated by snippetElision.
+func formatPlainSnippet(snippet []*snippetRange) string {
+	var parts []string
+	for _, r := range snippet {
+		parts = append(parts, strings.Join(r.lines, "\n"))
+	}
+	return strings.Join(parts, "\n"+snippetElision+"\n")
+}
+
+// Returns the snippet with a gutter of line numbers. If markSelected is set, selected lines are
+// marked with a leading "+" so that they are highlighted in a diff code block.
+func formatNumberedSnippet(snippet []*snippetRange, markSelected bool) string {
+	last := snippet[len(snippet)-1]
+	width := len(strconv.Itoa(last.begin + len(last.lines) - 1))
+	elision := fmt.Sprintf("%*s", width, snippetElision)
+	if markSelected {
+		elision = "  " + elision
+	}
+	var lines []string
+	for i, r := range snippet {
+		if i > 0 {
+			lines = append(lines, elision)
+		}
+		for j, line := range r.lines {
+			marker := ""
+			if markSelected {
+				marker = "  "
+				if r.selected[j] {
+					marker = "+ "
+				}
+			}
+			lines = append(lines, strings.TrimRight(
+				fmt.Sprintf("%v%*d │ %v", marker, width, r.begin+j, line), " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns the lines of the snippet for an image, highlighted as code of the language with the file
+// extension ext. Each range is highlighted on its own.
+func makeSnippetImageLines(
+	snippet []*snippetRange,
+	ext string,
+	markSelected bool,
+) []*codeimg.Line {
+	var lines []*codeimg.Line
+	for i, r := range snippet {
+		if i > 0 {
+			lines = append(lines, &codeimg.Line{Gap: true})
+		}
+		code := strings.Join(r.lines, "\n")
+		kinds := make([]codeimg.Kind, len(code)) // Plain between lexemes
+		for _, lexeme := range lexCode(code, ext) {
+			for k := lexeme.start; k < lexeme.end; k++ {
+				kinds[k] = lexemeSpanKinds[lexeme.kind]
+			}
+		}
+		start := 0
+		for j, text := range r.lines {
+			line := &codeimg.Line{Number: r.begin + j, Selected: markSelected && r.selected[j]}
+			for a := 0; a < len(text); {
+				b := a + 1
+				for b < len(text) && kinds[start+b] == kinds[start+a] {
+					b++
+				}
+				line.Spans = append(line.Spans, codeimg.Span{Text: text[a:b], Kind: kinds[start+a]})
+				a = b
+			}
+			lines = append(lines, line)
+			start += len(text) + 1
+		}
+	}
+	return lines
+}
+
+// Returns the number of lines of the formatted snippet.
+func snippetLineCount(snippet []*snippetRange) int {
+	count := len(snippet) - 1
+	for _, r := range snippet {
+		count += len(r.lines)
+	}
+	return count
+}
 
+// Returns the ranges clamped to the lines of the code and extended by contextLines on both sides,
+// sorted and with overlapping or adjacent ranges merged.
+func mergeLineRanges(ranges []fetch.LineRange, contextLines, numLines int) []fetch.LineRange {
+	extended := make([]fetch.LineRange, len(ranges))
+	for i, r := range ranges {
+		extended[i] = fetch.LineRange{
+			Begin: clamp(r.Begin-contextLines, 1, numLines),
+			End:   clamp(r.End+contextLines, 1, numLines),
+		}
+	}
+	sort.Slice(extended, func(i, j int) bool {
+		return extended[i].Begin < extended[j].Begin
+	})
+	var merged []fetch.LineRange
+	for _, r := range extended {
+		if last := len(merged) - 1; last >= 0 && r.Begin <= merged[last].End+1 {
+			if r.End > merged[last].End {
+				merged[last].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Returns the lines with the common leading whitespace removed, and whether they are all empty.
+func dedent(lines []string) (dedented []string, allEmpty bool) {
 	// This might not look nice if there are mixed spaces and tabs.
 	// But if you write such code, you deserve it.
 	minSpaceCount := math.MaxInt32
@@ -221,29 +551,25 @@ func makeCodeSnippet(code string, begin, end int) (snippet string, numLines int,
 			}
 		}
 	}
-	allEmpty := true
-	for i := range lines {
-		if len(lines[i]) > minSpaceCount {
-			lines[i] = lines[i][minSpaceCount:]
+	allEmpty = true
+	dedented = make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) > minSpaceCount {
+			dedented[i] = line[minSpaceCount:]
 			allEmpty = false
-		} else {
-			lines[i] = ""
 		}
 	}
-	if allEmpty {
-		return "", 0, errSelectionEmpty
-	}
-
-	return strings.Join(lines, "\n"), len(lines), nil
+	return
 }
 
+// Returns the snippet in a code block of the language, which is a code block language like "cpp".
 func makeContent(snippet, language string) string {
-	return "```" + languageNameToExt[language] + "\n" + snippet + "```"
+	return "```" + language + "\n" + snippet + "```"
 }
 
-func makeFilename(id, language string) string {
-	var ext string
-	if ext = languageNameToExt[language]; ext == "" {
+// Returns the filename of a snippet, with the file extension from languageExt.
+func makeFilename(id, ext string) string {
+	if ext == "" {
 		ext = "txt"
 	}
 	return "snippet_" + id + "." + ext
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"image/png"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/andersfylling/disgord"
 	"github.com/go-test/deep"
+	"github.com/meooow25/cfspy/codeimg"
 	"github.com/meooow25/cfspy/fetch"
 )
 
@@ -19,9 +22,16 @@ var (
 		Color:  0x123456,
 	}
 	testAuthorTeam = &fetch.SubmissionInfoTeam{
+		ID:   "42",
 		Name: "worst team ever",
+		URL:  "https://codeforces.com/team/42",
 		Authors: []*fetch.SubmissionInfoAuthor{
-			{Handle: "member1", Color: 0x010203},
+			{
+				Handle: "member1",
+				Color:  0x010203,
+				Rank:   "Pupil",
+				URL:    "https://codeforces.com/profile/member1",
+			},
 			{Handle: "member2", Color: 0x030201},
 		},
 	}
@@ -104,6 +114,37 @@ func newWantEmbed(title, description string, color int) *disgord.Embed {
 	}
 }
 
+func lines(begin, end int) []fetch.LineRange {
+	return []fetch.LineRange{{Begin: begin, End: end}}
+}
+
+// Returns lines of testContent numbered like in a snippet, with the given gutter width.
+func numberedTestLines(begin, end, width int) string {
+	lines := strings.Split(testContent, "\n")
+	var numbered []string
+	for i := begin; i <= end; i++ {
+		numbered = append(numbered, strings.TrimRight(
+			fmt.Sprintf("%*d │ %v", width, i, lines[i-1]), " "))
+	}
+	return strings.Join(numbered, "\n")
+}
+
+// Returns lines of testContent numbered like in a snippet with context, the lines in the selected
+// ranges, given as begin and end pairs, marked.
+func markedTestLines(begin, end, width int, selected ...int) string {
+	lines := strings.Split(numberedTestLines(begin, end, width), "\n")
+	for i := range lines {
+		marker := "  "
+		for j := 0; j < len(selected); j += 2 {
+			if selected[j] <= begin+i && begin+i <= selected[j+1] {
+				marker = "+ "
+			}
+		}
+		lines[i] = marker + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
 func testContentLines(start, end int) string {
 	return strings.Join(strings.Split(testContent, "\n")[start-1:end], "\n")
 }
@@ -113,9 +154,9 @@ func TestMakeSubmissionResponse(t *testing.T) {
 	tests := []struct {
 		name string
 
-		info      *fetch.SubmissionInfo
-		lineBegin int
-		lineEnd   int
+		info         *fetch.SubmissionInfo
+		lines        []fetch.LineRange
+		contextLines int
 
 		wantContent string
 		wantEmbed   *disgord.Embed
@@ -152,11 +193,20 @@ func TestMakeSubmissionResponse(t *testing.T) {
 		{
 			name: "summaryAuthorTeam",
 			info: newSubmissionInfo(authorTeam(testAuthorTeam)),
-			wantEmbed: newWantEmbed(
-				"Submission for 4321Z by worst team ever: member1, member2",
-				"Verdict • Contestant • Go",
-				teamColor,
-			),
+			wantEmbed: func() *disgord.Embed {
+				embed := newWantEmbed("Submission for 4321Z by worst team ever",
+					"Verdict • Contestant • Go", teamColor)
+				embed.Fields = []*disgord.EmbedField{
+					{Name: "Team", Value: "[worst team ever](https://codeforces.com/team/42)"},
+					{
+						Name:   "Pupil",
+						Value:  "[member1](https://codeforces.com/profile/member1)",
+						Inline: true,
+					},
+					{Name: "Member", Value: "member2", Inline: true},
+				}
+				return embed
+			}(),
 		},
 		{
 			name: "summaryAuthorGhost",
@@ -167,87 +217,150 @@ func TestMakeSubmissionResponse(t *testing.T) {
 				ghostColor,
 			),
 		},
+		{
+			name: "summaryDetails",
+			info: newSubmissionInfo(func(info *fetch.SubmissionInfo) {
+				info.ProblemURL = "https://codeforces.com/contest/4321/problem/Z"
+				info.ContestID = "4321"
+				info.Time = 93 * time.Millisecond
+				info.Memory = 4
+				info.JudgedTime = time.Unix(1600000000, 0)
+			}),
+			wantEmbed: func() *disgord.Embed {
+				embed := newWantEmbed("Submission for 4321Z by author", "Verdict • Contestant • Go",
+					testAuthor.Color)
+				embed.Fields = []*disgord.EmbedField{
+					{
+						Name:  "Problem",
+						Value: "[4321Z](https://codeforces.com/contest/4321/problem/Z) in [contest 4321](https://codeforces.com/contest/4321)",
+					},
+					{Name: "Time", Value: "93 ms", Inline: true},
+					{Name: "Memory", Value: "4 KB", Inline: true},
+					{Name: "Judged", Value: "<t:1600000000:f>", Inline: true},
+				}
+				return embed
+			}(),
+		},
 		{
 			name:        "snippetShort",
 			info:        newSubmissionInfo(),
-			lineBegin:   12,
-			lineEnd:     15,
-			wantContent: "```go\n" + testContentLines(12, 15) + "```",
+			lines:       lines(12, 15),
+			wantContent: "```go\n" + numberedTestLines(12, 15, 2) + "```",
 		},
 		{
 			name:        "snippetShortLineNumsClamped",
 			info:        newSubmissionInfo(content("code")),
-			lineBegin:   -100,
-			lineEnd:     100,
-			wantContent: "```go\ncode```",
+			lines:       lines(-100, 100),
+			wantContent: "```go\n1 │ code```",
 		},
 		{
 			name:        "snippetShortNoExtMapped",
 			info:        newSubmissionInfo(language("HolyC")),
-			lineBegin:   12,
-			lineEnd:     15,
-			wantContent: "```\n" + testContentLines(12, 15) + "```",
+			lines:       lines(12, 15),
+			wantContent: "```\n" + numberedTestLines(12, 15, 2) + "```",
 		},
 		{
 			name:        "snippetShortMaxLines",
 			info:        newSubmissionInfo(),
-			lineBegin:   12,
-			lineEnd:     12 + maxSnippetMsgLines - 1,
-			wantContent: "```go\n" + testContentLines(12, 12+maxSnippetMsgLines-1) + "```",
+			lines:       lines(12, 12+maxSnippetMsgLines-1),
+			wantContent: "```go\n" + numberedTestLines(12, 12+maxSnippetMsgLines-1, 2) + "```",
 		},
 		{
-			name:      "snippetLongMinLines",
-			info:      newSubmissionInfo(),
-			lineBegin: 12,
-			lineEnd:   12 + maxSnippetMsgLines,
+			name:  "snippetLongMinLines",
+			info:  newSubmissionInfo(),
+			lines: lines(12, 12+maxSnippetMsgLines),
 			wantFile: &file{
 				name:    "snippet_998244353.go",
 				content: testContentLines(12, 12+maxSnippetMsgLines),
 			},
 		},
 		{
-			name:      "snippetLong",
-			info:      newSubmissionInfo(),
-			lineBegin: 12,
-			lineEnd:   365,
+			name:  "snippetLong",
+			info:  newSubmissionInfo(),
+			lines: lines(12, 365),
 			wantFile: &file{
 				name:    "snippet_998244353.go",
 				content: testContentLines(12, 365),
 			},
 		},
 		{
-			name:      "snippetLongNoExtMapped",
-			info:      newSubmissionInfo(language("HolyC")),
-			lineBegin: 12,
-			lineEnd:   365,
+			name:  "snippetLongNoExtMapped",
+			info:  newSubmissionInfo(language("HolyC")),
+			lines: lines(12, 365),
 			wantFile: &file{
 				name:    "snippet_998244353.txt",
 				content: testContentLines(12, 365),
 			},
 		},
 		{
-			name:      "snippetLongOneLine",
-			info:      newSubmissionInfo(content(testContentLongLine)),
-			lineBegin: 1,
-			lineEnd:   1,
+			name:  "snippetLongOneLine",
+			info:  newSubmissionInfo(content(testContentLongLine)),
+			lines: lines(1, 1),
 			wantFile: &file{
 				name:    "snippet_998244353.go",
 				content: testContentLongLine,
 			},
 		},
 		{
-			name:      "snippetEmpty",
-			info:      newSubmissionInfo(),
-			lineBegin: 201,
-			lineEnd:   201,
-			wantErr:   errSelectionEmpty,
+			name:    "snippetEmpty",
+			info:    newSubmissionInfo(),
+			lines:   lines(201, 201),
+			wantErr: errSelectionEmpty,
+		},
+		{
+			name:  "snippetRanges",
+			info:  newSubmissionInfo(),
+			lines: []fetch.LineRange{{Begin: 30, End: 31}, {Begin: 5, End: 6}},
+			wantContent: "```go\n" + numberedTestLines(5, 6, 2) + "\n ⋮\n" +
+				numberedTestLines(30, 31, 2) + "```",
+		},
+		{
+			name:         "snippetRangesWithContext",
+			info:         newSubmissionInfo(),
+			lines:        []fetch.LineRange{{Begin: 5, End: 6}, {Begin: 10, End: 10}, {Begin: 30, End: 30}},
+			contextLines: 2,
+			wantContent: "```diff\n" +
+				markedTestLines(3, 12, 2, 5, 6, 10, 10) + "\n   ⋮\n" +
+				markedTestLines(28, 32, 2, 30, 30) + "```",
+		},
+		{
+			name:         "snippetContextClamped",
+			info:         newSubmissionInfo(),
+			lines:        lines(1, 1),
+			contextLines: 1000,
+			wantContent:  "```diff\n" + markedTestLines(1, 1+maxContextLines, 2, 1, 1) + "```",
+		},
+		{
+			name:        "snippetRangesDedentedSeparately",
+			info:        newSubmissionInfo(content("  a\n    b\n\tc\n\t\td")),
+			lines:       []fetch.LineRange{{Begin: 1, End: 2}, {Begin: 4, End: 4}},
+			wantContent: "```go\n1 │ a\n2 │   b\n⋮\n4 │ d```",
+		},
+		{
+			name:         "snippetWithContextLong",
+			info:         newSubmissionInfo(),
+			lines:        lines(100, 120),
+			contextLines: 5,
+			wantFile: &file{
+				name:    "snippet_998244353.go",
+				content: testContentLines(95, 125),
+			},
+		},
+		{
+			name:  "snippetRangesLong",
+			info:  newSubmissionInfo(),
+			lines: []fetch.LineRange{{Begin: 10, End: 24}, {Begin: 40, End: 54}},
+			wantFile: &file{
+				name:    "snippet_998244353.go",
+				content: testContentLines(10, 24) + "\n⋮\n" + testContentLines(40, 54),
+			},
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			content, embed, file, err :=
-				makeSubmissionResponse(test.info, test.lineBegin, test.lineEnd)
+				makeSubmissionResponse(test.info, test.lines, test.contextLines)
 
 			eq := func(a, b interface{}) {
 				if diff := deep.Equal(a, b); diff != nil {
@@ -271,3 +384,125 @@ func TestMakeSubmissionResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeSubmissionResponseNumberedFile(t *testing.T) {
+	numberFileSnippets = true
+	defer func() { numberFileSnippets = false }()
+
+	_, _, file, err := makeSubmissionResponse(newSubmissionInfo(), lines(12, 365), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(file.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := numberedTestLines(12, 365, 3); string(got) != want {
+		t.Fatalf("got\n%v\nwant\n%v", string(got), want)
+	}
+}
+
+func TestMakeSubmissionResponseImage(t *testing.T) {
+	snippetImageTheme = codeimg.Themes["dark"]
+	defer func() { snippetImageTheme = nil }()
+
+	content, embed, file, err := makeSubmissionResponse(newSubmissionInfo(), lines(12, 15), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "" || embed != nil || file == nil || !strings.HasSuffix(file.FileName, ".png") {
+		t.Fatalf("got content %q and file %+v, want an image", content, file)
+	}
+	data, err := ioutil.ReadAll(file.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Runes the font cannot draw are sent as text
+	info := newSubmissionInfo(func(info *fetch.SubmissionInfo) {
+		info.Content = "// 漢字\nint x;\n"
+	})
+	content, _, file, err = makeSubmissionResponse(info, lines(1, 2), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != nil || !strings.Contains(content, "漢字") {
+		t.Fatalf("got content %q and file %+v, want the code as text", content, file)
+	}
+}
+
+func TestMakeSnippetImageLines(t *testing.T) {
+	snippet := []*snippetRange{
+		{
+			begin:    3,
+			lines:    []string{"int x; // one", "/* a", "b */ x++;"},
+			selected: []bool{true, false, false},
+		},
+		{begin: 9, lines: []string{`s = "q"`}, selected: []bool{true}},
+	}
+	lines := makeSnippetImageLines(snippet, "cpp", true)
+	want := []*codeimg.Line{
+		{Number: 3, Selected: true, Spans: []codeimg.Span{
+			{Text: "int", Kind: codeimg.Keyword},
+			{Text: " x", Kind: codeimg.Plain},
+			{Text: ";", Kind: codeimg.Punct},
+			{Text: " ", Kind: codeimg.Plain},
+			{Text: "// one", Kind: codeimg.Comment},
+		}},
+		{Number: 4, Spans: []codeimg.Span{{Text: "/* a", Kind: codeimg.Comment}}},
+		{Number: 5, Spans: []codeimg.Span{
+			{Text: "b */", Kind: codeimg.Comment},
+			{Text: " x", Kind: codeimg.Plain},
+			{Text: "++;", Kind: codeimg.Punct},
+		}},
+		{Gap: true},
+		{Number: 9, Selected: true, Spans: []codeimg.Span{
+			{Text: "s ", Kind: codeimg.Plain},
+			{Text: "=", Kind: codeimg.Punct},
+			{Text: " ", Kind: codeimg.Plain},
+			{Text: `"q"`, Kind: codeimg.String},
+		}},
+	}
+	if diff := deep.Equal(lines, want); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestSubmissionJudging(t *testing.T) {
+	for _, test := range []struct {
+		verdict, kind string
+		want          bool
+	}{
+		{"In queue", "In queue", true},
+		{"Running on test 12", "Running", true},
+		{"Wrong answer on test 3", "Wrong answer", false},
+		{"Accepted", "Accepted", false},
+	} {
+		info := &fetch.SubmissionInfo{
+			Verdict:     test.verdict,
+			VerdictKind: test.kind,
+			Author:      &fetch.SubmissionInfoAuthor{Handle: "tourist"},
+		}
+		if got := submissionJudging(info); got != test.want {
+			t.Errorf("%v: got judging %v, want %v", test.verdict, got, test.want)
+		}
+		embed, err := makeSubmissionEmbed(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.HasPrefix(embed.Description, "⏳ "); got != test.want {
+			t.Errorf("%v: got description %q", test.verdict, embed.Description)
+		}
+	}
+
+	delay := minVerdictPollDelay
+	for i := 0; i < 10; i++ {
+		delay = nextPollDelay(delay)
+	}
+	if delay != maxVerdictPollDelay {
+		t.Fatalf("got delay %v, want %v", delay, maxVerdictPollDelay)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andersfylling/disgord"
+	"github.com/meooow25/cfspy/bot"
+	"github.com/meooow25/cfspy/fetch"
+)
+
+// Widget kind for submission diffs.
+const submissionDiffWidgetKind = "submissiondiff"
+
+// The lines of context around changes in submission diffs.
+const diffContextLines = 3
+
+// Installs the diff command, which shows a diff between two submissions. Messages with two
+// submission links are handled by the submission feature.
+func installSubmissionDiffCommand(b *bot.Bot) {
+	b.Client.Logger().Info("Setting up diff command")
+	b.AddCommand(&bot.Command{
+		ID:          "diff",
+		Usage:       "<submission url> <submission url>",
+		Description: "Shows the changes between two submissions",
+		Handler:     onSubmissionDiff,
+	})
+	b.AddWidgetResumer(submissionDiffWidgetKind, resumeSubmissionDiffWidget)
+}
+
+func onSubmissionDiff(ctx *bot.Context) {
+	go func() {
+		if len(ctx.Args) != 3 {
+			ctx.SendIncorrectUsageMsg()
+			return
+		}
+		var urls []string
+		for _, arg := range ctx.Args[1:] {
+			matches := fetch.ParseSubmissionURLs(arg)
+			if len(matches) != 1 {
+				ctx.SendIncorrectUsageMsg()
+				return
+			}
+			urls = append(urls, matches[0].URL)
+		}
+		handleSubmissionDiff(ctx, urls[0], urls[1])
+	}()
+}
+
+// Fetches both submissions and responds on the Discord channel with the diff between them.
+func handleSubmissionDiff(ctx *bot.Context, oldURL, newURL string) {
+	ctx.Logger.Info("Processing submission diff: ", oldURL, " ", newURL)
+
+	params, err := makeSubmissionDiffPreviewParams(ctx, oldURL, newURL)
+	if err != nil {
+		ctx.Logger.Error(err)
+		respondWithError(ctx, err)
+		return
+	}
+	source := oldURL + " " + newURL
+	if err = respondWithPreview(ctx, submissionDiffWidgetKind, source, params); err != nil {
+		ctx.Logger.Error(fmt.Errorf("Error sending submission diff: %w", err))
+	}
+}
+
+// Rebuilds a submission diff from the two URLs.
+func resumeSubmissionDiffWidget(
+	ctx *bot.Context,
+	state *bot.WidgetState,
+) (*bot.WidgetParams, error) {
+	urls := strings.Fields(state.Source)
+	if len(urls) != 2 {
+		return nil, fmt.Errorf("Not two submission URLs: %v", state.Source)
+	}
+	return makeSubmissionDiffPreviewParams(ctx, urls[0], urls[1])
+}
+
+func makeSubmissionDiffPreviewParams(
+	ctx *bot.Context,
+	oldURL, newURL string,
+) (*bot.WidgetParams, error) {
+	infos, err := fetchSubmissions(oldURL, newURL)
+	if err != nil {
+		return nil, err
+	}
+
+	content, file := makeSubmissionDiffResponse(infos[0], infos[1])
+	page := bot.NewPage(content, nil)
+	if file != nil {
+		return makeOnePagePreviewParams(ctx, page, *file), nil
+	}
+	return makeOnePagePreviewParams(ctx, page), nil
+}
+
+// Returns a unified diff from the old submission to the new one, in a diff code block if it fits
+// in a message or as a file otherwise.
+func makeSubmissionDiffResponse(
+	oldInfo, newInfo *fetch.SubmissionInfo,
+) (string, *disgord.CreateMessageFileParams) {
+	hunks := unifiedDiff(codeLines(oldInfo.Content), codeLines(newInfo.Content), diffContextLines)
+	if hunks == "" {
+		return fmt.Sprintf("Submissions %v and %v have the same code", oldInfo.ID, newInfo.ID), nil
+	}
+	diff := "--- " + makeDiffHeader(oldInfo) + "\n+++ " + makeDiffHeader(newInfo) + "\n" + hunks
+
+	// Content ok in a message, send
+	content := makeContent(diff, "diff")
+	if strings.Count(diff, "\n")+1 <= maxSnippetMsgLines && !bot.ContentTooLong(content) {
+		return content, nil
+	}
+
+	// Content too large or ugly in a message, send as file
+	file := &disgord.CreateMessageFileParams{
+		Reader:   strings.NewReader(diff + "\n"),
+		FileName: "diff_" + oldInfo.ID + "_" + newInfo.ID + ".diff",
+	}
+	return "", file
+}
+
+// Returns the header of a submission in a diff, like "123456 (Accepted, GNU C++17)".
+func makeDiffHeader(info *fetch.SubmissionInfo) string {
+	return fmt.Sprintf("%v (%v, %v)", info.ID, info.Verdict, info.Language)
+}
+
+// Returns the lines of the code, with Windows line endings and trailing whitespace removed.
+func codeLines(code string) []string {
+	lines := strings.Split(strings.TrimRight(code, "\r\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return lines
+}
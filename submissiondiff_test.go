@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/meooow25/cfspy/fetch"
+)
+
+func TestMakeSubmissionDiffResponse(t *testing.T) {
+	wa := &fetch.SubmissionInfo{
+		ID:       "1",
+		Verdict:  "Wrong answer on test 3",
+		Language: "GNU C++17",
+		Content:  "int a;\r\nint b;\r\nint c;\r\n",
+	}
+	ac := &fetch.SubmissionInfo{
+		ID:       "2",
+		Verdict:  "Accepted",
+		Language: "GNU C++17",
+		Content:  "int a;\nlong long b;\nint c;\n",
+	}
+
+	content, file := makeSubmissionDiffResponse(wa, ac)
+	want := "```diff\n" +
+		"--- 1 (Wrong answer on test 3, GNU C++17)\n" +
+		"+++ 2 (Accepted, GNU C++17)\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" int a;\n" +
+		"-int b;\n" +
+		"+long long b;\n" +
+		" int c;```"
+	if content != want || file != nil {
+		t.Fatalf("got\n%v\nwant\n%v", content, want)
+	}
+
+	if content, file := makeSubmissionDiffResponse(wa, wa); file != nil ||
+		content != "Submissions 1 and 1 have the same code" {
+		t.Fatalf("got %q for the same code", content)
+	}
+
+	var oldLines, newLines []string
+	for i := 0; i < maxSnippetMsgLines; i++ {
+		oldLines = append(oldLines, fmt.Sprint(i+1))
+		newLines = append(newLines, fmt.Sprint(-i))
+	}
+	wa.Content = strings.Join(oldLines, "\n")
+	ac.Content = strings.Join(newLines, "\n")
+	content, file = makeSubmissionDiffResponse(wa, ac)
+	if content != "" || file == nil || file.FileName != "diff_1_2.diff" {
+		t.Fatalf("got content %q, want a file", content)
+	}
+	b, err := ioutil.ReadAll(file.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "--- 1 (Wrong answer on test 3, GNU C++17)\n") {
+		t.Fatalf("got file\n%v", string(b))
+	}
+}
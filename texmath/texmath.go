@@ -0,0 +1,182 @@
+// Package texmath is the front end shared by the conversions of TeX math: the tokenizer, the
+// parser helpers and the table of symbol commands. It is used to show math both as Unicode text
+// and as images.
+package texmath
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrUnsupported is returned for TeX that cannot be converted.
+var ErrUnsupported = errors.New("Unsupported TeX")
+
+// Class is the class of a math atom, which decides the spacing around it.
+type Class int
+
+// Math atom classes.
+const (
+	ClassOrd Class = iota
+	ClassOp
+	ClassBin
+	ClassRel
+	ClassPunct
+)
+
+// Symbol is what a symbol command stands for.
+type Symbol struct {
+	Text  string
+	Class Class
+}
+
+// Symbols maps commands to the symbols they stand for.
+var Symbols = map[string]Symbol{
+	// Greek
+	`\alpha`: {"α", ClassOrd}, `\beta`: {"β", ClassOrd}, `\gamma`: {"γ", ClassOrd},
+	`\delta`: {"δ", ClassOrd}, `\epsilon`: {"ϵ", ClassOrd}, `\varepsilon`: {"ε", ClassOrd},
+	`\zeta`: {"ζ", ClassOrd}, `\eta`: {"η", ClassOrd}, `\theta`: {"θ", ClassOrd},
+	`\vartheta`: {"ϑ", ClassOrd}, `\iota`: {"ι", ClassOrd}, `\kappa`: {"κ", ClassOrd},
+	`\lambda`: {"λ", ClassOrd}, `\mu`: {"μ", ClassOrd}, `\nu`: {"ν", ClassOrd},
+	`\xi`: {"ξ", ClassOrd}, `\pi`: {"π", ClassOrd}, `\rho`: {"ρ", ClassOrd},
+	`\sigma`: {"σ", ClassOrd}, `\tau`: {"τ", ClassOrd}, `\upsilon`: {"υ", ClassOrd},
+	`\phi`: {"ϕ", ClassOrd}, `\varphi`: {"φ", ClassOrd}, `\chi`: {"χ", ClassOrd},
+	`\psi`: {"ψ", ClassOrd}, `\omega`: {"ω", ClassOrd}, `\Gamma`: {"Γ", ClassOrd},
+	`\Delta`: {"Δ", ClassOrd}, `\Theta`: {"Θ", ClassOrd}, `\Lambda`: {"Λ", ClassOrd},
+	`\Xi`: {"Ξ", ClassOrd}, `\Pi`: {"Π", ClassOrd}, `\Sigma`: {"Σ", ClassOrd},
+	`\Upsilon`: {"Υ", ClassOrd}, `\Phi`: {"Φ", ClassOrd}, `\Psi`: {"Ψ", ClassOrd},
+	`\Omega`: {"Ω", ClassOrd},
+
+	// Operators
+	`\cdot`: {"·", ClassBin}, `\times`: {"×", ClassBin}, `\div`: {"÷", ClassBin},
+	`\pm`: {"±", ClassBin}, `\mp`: {"∓", ClassBin}, `\oplus`: {"⊕", ClassBin},
+	`\otimes`: {"⊗", ClassBin}, `\circ`: {"∘", ClassBin}, `\ast`: {"∗", ClassBin},
+	`\cup`: {"∪", ClassBin}, `\cap`: {"∩", ClassBin}, `\setminus`: {"∖", ClassBin},
+	`\land`: {"∧", ClassBin}, `\lor`: {"∨", ClassBin}, `\wedge`: {"∧", ClassBin},
+	`\vee`: {"∨", ClassBin}, `\bmod`: {" mod ", ClassBin}, `\mod`: {" mod ", ClassBin},
+	`\sum`: {"∑", ClassOp}, `\prod`: {"∏", ClassOp}, `\int`: {"∫", ClassOp},
+	`\bigcup`: {"⋃", ClassOp}, `\bigcap`: {"⋂", ClassOp},
+
+	// Relations
+	`\le`: {"≤", ClassRel}, `\leq`: {"≤", ClassRel}, `\leqslant`: {"≤", ClassRel},
+	`\ge`: {"≥", ClassRel}, `\geq`: {"≥", ClassRel}, `\geqslant`: {"≥", ClassRel},
+	`\ne`: {"≠", ClassRel}, `\neq`: {"≠", ClassRel}, `\lt`: {"<", ClassRel},
+	`\gt`: {">", ClassRel}, `\approx`: {"≈", ClassRel}, `\equiv`: {"≡", ClassRel},
+	`\sim`: {"∼", ClassRel}, `\ll`: {"≪", ClassRel}, `\gg`: {"≫", ClassRel},
+	`\in`: {"∈", ClassRel}, `\notin`: {"∉", ClassRel}, `\ni`: {"∋", ClassRel},
+	`\subset`: {"⊂", ClassRel}, `\subseteq`: {"⊆", ClassRel}, `\supset`: {"⊃", ClassRel},
+	`\supseteq`: {"⊇", ClassRel}, `\mid`: {"∣", ClassRel}, `\to`: {"→", ClassRel},
+	`\rightarrow`: {"→", ClassRel}, `\leftarrow`: {"←", ClassRel}, `\Rightarrow`: {"⇒", ClassRel},
+	`\Leftarrow`: {"⇐", ClassRel}, `\iff`: {"⇔", ClassRel}, `\Leftrightarrow`: {"⇔", ClassRel},
+	`\implies`: {"⇒", ClassRel},
+
+	// Other symbols
+	`\infty`: {"∞", ClassOrd}, `\emptyset`: {"∅", ClassOrd}, `\varnothing`: {"∅", ClassOrd},
+	`\forall`: {"∀", ClassOrd}, `\exists`: {"∃", ClassOrd}, `\neg`: {"¬", ClassOrd},
+	`\lnot`: {"¬", ClassOrd}, `\partial`: {"∂", ClassOrd}, `\nabla`: {"∇", ClassOrd},
+	`\prime`: {"′", ClassOrd}, `\ldots`: {"…", ClassOrd}, `\dots`: {"…", ClassOrd},
+	`\cdots`: {"⋯", ClassOrd}, `\vdots`: {"⋮", ClassOrd}, `\lfloor`: {"⌊", ClassOrd},
+	`\rfloor`: {"⌋", ClassOrd}, `\lceil`: {"⌈", ClassOrd}, `\rceil`: {"⌉", ClassOrd},
+	`\langle`: {"⟨", ClassOrd}, `\rangle`: {"⟩", ClassOrd}, `\{`: {"{", ClassOrd},
+	`\}`: {"}", ClassOrd}, `\|`: {"‖", ClassOrd}, `\%`: {"%", ClassOrd}, `\_`: {"_", ClassOrd},
+	`\#`: {"#", ClassOrd}, `\&`: {"&", ClassOrd}, `\$`: {"$", ClassOrd},
+}
+
+// Spaces maps spacing and sizing commands to the width of space they add, in mu (1/18 em).
+// Sizing commands add none.
+var Spaces = map[string]float64{
+	`\,`: 3, `\:`: 4, `\;`: 5, `\ `: 6, `\quad`: 18, `\qquad`: 36, `\!`: 0,
+	`\left`: 0, `\right`: 0, `\big`: 0, `\Big`: 0, `\bigg`: 0, `\Bigg`: 0,
+	`\displaystyle`: 0, `\limits`: 0, `\nolimits`: 0,
+}
+
+// Functions are shown by name, upright.
+var Functions = map[string]bool{
+	`\log`: true, `\ln`: true, `\lg`: true, `\exp`: true, `\sin`: true, `\cos`: true, `\tan`: true,
+	`\max`: true, `\min`: true, `\gcd`: true, `\lcm`: true, `\deg`: true, `\det`: true,
+	`\lim`: true, `\sup`: true, `\inf`: true, `\arg`: true,
+}
+
+// TextCommands take an argument that is shown as text.
+var TextCommands = map[string]bool{
+	`\text`: true, `\textrm`: true, `\textit`: true, `\textbf`: true, `\mathrm`: true,
+	`\mathit`: true, `\mathbf`: true, `\mathsf`: true, `\mathtt`: true, `\operatorname`: true,
+	`\texttt`: true, `\mathbb`: true, `\mathcal`: true,
+}
+
+// Tokenize splits TeX into commands like \alpha or \{, and single runes.
+func Tokenize(tex string) []string {
+	var tokens []string
+	runes := []rune(tex)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' || i+1 == len(runes) {
+			tokens = append(tokens, string(runes[i]))
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && unicode.IsLetter(runes[j]) {
+			j++
+		}
+		if j == i+1 {
+			j++ // A command of one symbol, like \{
+		}
+		tokens = append(tokens, string(runes[i:j]))
+		i = j - 1
+	}
+	return tokens
+}
+
+// Parser walks the tokens of TeX. It is embedded by the parsers that convert math.
+type Parser struct {
+	tokens []string
+	pos    int
+}
+
+// NewParser returns a parser at the first token of tex.
+func NewParser(tex string) Parser {
+	return Parser{tokens: Tokenize(tex)}
+}
+
+// Peek returns the current token, or "" at the end.
+func (p *Parser) Peek() string {
+	if p.pos == len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// Next returns the current token, or "" at the end, and moves past it.
+func (p *Parser) Next() string {
+	token := p.Peek()
+	if token != "" {
+		p.pos++
+	}
+	return token
+}
+
+// SkipSpace moves past whitespace tokens.
+func (p *Parser) SkipSpace() {
+	for p.Peek() != "" && strings.TrimSpace(p.Peek()) == "" {
+		p.pos++
+	}
+}
+
+// ParseRawGroup parses a group as text, keeping spaces.
+func (p *Parser) ParseRawGroup() (string, error) {
+	p.SkipSpace()
+	if p.Next() != "{" {
+		return "", fmt.Errorf("%w: expected {", ErrUnsupported)
+	}
+	var b strings.Builder
+	for token := p.Next(); token != "}"; token = p.Next() {
+		if token == "" {
+			return "", fmt.Errorf("%w: unmatched {", ErrUnsupported)
+		}
+		if sym, ok := Symbols[token]; ok && len(token) == 2 {
+			token = sym.Text // Escaped symbols like \%
+		}
+		b.WriteString(token)
+	}
+	return b.String(), nil
+}
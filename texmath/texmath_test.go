@@ -0,0 +1,50 @@
+package texmath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestTokenize(t *testing.T) {
+	for _, test := range []struct {
+		tex  string
+		want []string
+	}{
+		{`a_i`, []string{"a", "_", "i"}},
+		{`\alpha\{x\}`, []string{`\alpha`, `\{`, "x", `\}`}},
+		{`\le 10`, []string{`\le`, " ", "1", "0"}},
+		{`\, \ β`, []string{`\,`, " ", `\ `, "β"}},
+		{`x\`, []string{"x", `\`}},
+	} {
+		if diff := deep.Equal(Tokenize(test.tex), test.want); diff != nil {
+			t.Errorf("%v: %v", test.tex, diff)
+		}
+	}
+}
+
+func TestParseRawGroup(t *testing.T) {
+	p := NewParser(` {100\% of n} x`)
+	got, err := p.ParseRawGroup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "100% of n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	p.SkipSpace()
+	if token := p.Next(); token != "x" {
+		t.Errorf("got next token %q, want %q", token, "x")
+	}
+	if token := p.Next(); token != "" {
+		t.Errorf("got token %q at the end, want none", token)
+	}
+
+	for _, tex := range []string{`x`, `{x`} {
+		p := NewParser(tex)
+		if _, err := p.ParseRawGroup(); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%v: got error %v, want %v", tex, err, ErrUnsupported)
+		}
+	}
+}